@@ -6,17 +6,24 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/ducng99/goserve/cmd/serve"
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/fulltext"
 	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use: fmt.Sprintf("goserve [flags] [host:port]\n\nDefault host:port is \"%s:%s\"", serve.DefaultListenHost, serve.DefaultListenPort),
+	Use: fmt.Sprintf("goserve [flags] [address...]\n\nAddress can be host:port, unix:/path/to.sock or systemd[:name] (socket activation).\nDefault address is \"%s:%s\"", serve.DefaultListenHost, serve.DefaultListenPort),
 	Example: `Start server with HTTPS on port 8443:
 goserve -cd /path/to/dir --https --sslcert full-cert.crt --sslkey private-key.key localhost:8443
 
 Proxy to another server on port 8080, and listen on port 8081:
-goserve -p http://localhost:8080 localhost:8081`,
+goserve -p http://localhost:8080 localhost:8081
+
+Listen on a Unix socket and on localhost port 8080:
+goserve --socket-mode 0660 unix:/run/goserve.sock localhost:8080`,
 	Short: "Starts a web server to serve static files",
 	Long:  "Starts a web server to serve static files, with options for HTTPS, directory, CORS, and more.",
 	Run:   serve.HandleCommand,
@@ -39,9 +46,29 @@ func init() {
 	flags.SortFlags = false
 
 	// Generic server configs
-	flags.StringP("dir", "d", ".", "Directory to serve")
+	flags.StringArrayP("dir", "d", []string{"."}, "Directory or archive (.zip, .tar, .tar.gz) to serve.\nRepeat to layer roots, a path is served from the first root containing it")
+	flags.String("git", "", "Git repository to serve a revision of, read from its objects instead of the working copy")
+	flags.String("ref", "HEAD", "Git ref to serve with --git, e.g. a branch, tag or commit.\nOther refs can be browsed with the ref switcher or ?ref=")
+	rootCmd.MarkFlagsMutuallyExclusive("dir", "git")
+	flags.Bool("hide-dotfiles", false, "Hide files and directories starting with '.'")
+	flags.StringArray("exclude", []string{}, "Hide paths matching the pattern, in .gitignore syntax (e.g. '*.swp' or '/secrets/').\nHidden paths are not listed and return 404")
+	flags.Bool("ignore-files", false, "Hide paths ignored by .gitignore and .goserveignore files, and the .git directory")
+	flags.String("symlinks", string(files.SymlinksWithinRoot), "How to treat symlinks: never (do not follow or list), root (follow within the root),\nallow (also follow into --symlink-allow directories) or show (list with their target, do not follow)")
+	flags.StringArray("symlink-allow", []string{}, "Directory symlinks may lead to besides the root. Implies --symlinks allow")
 	flags.BoolP("cors", "c", false, "Set CORS headers")
-	flags.String("index-theme", "pretty", "Directory index page theme.\nAvailable themes: basic, pretty")
+	flags.String("port", serve.DefaultListenPort, "Port for addresses without one.\nUse '"+serve.PortAuto+"' to take the next free port when "+serve.DefaultListenPort+" is in use")
+	flags.Bool("qr", false, "Print a QR code of the LAN URL, to open it from a phone")
+	flags.String("socket-mode", "", "File mode for Unix sockets, in octal (e.g. 0660)")
+	flags.String("index-theme", "pretty", "Directory index page theme.\nAvailable themes: basic, pretty, gallery")
+	flags.String("index-template", "", "Go html/template file, or directory with an index.html template and assets, rendering directory listings.\nOverrides --index-theme for listings")
+	flags.Int("page-size", server.DefaultPageSize, "Entries per directory listing page, 0 lists every entry.\nPages can be picked with ?page= and ?per_page=")
+	flags.Int("search-depth", server.DefaultSearchDepth, "Levels of sub-directories searched by ?search=")
+	flags.Int("search-results", server.DefaultSearchResults, "Matches shown by ?search= before it stops")
+	flags.Duration("search-timeout", server.DefaultSearchTimeout, "Time ?search= walks the tree before it stops")
+	flags.Bool("text-index", false, "Index text files in the background to search their contents with ?search=...&content=1")
+	flags.Int64("text-index-max-size", fulltext.DefaultMaxFileSize, "Largest file indexed by --text-index, in bytes")
+	flags.Duration("text-index-interval", fulltext.DefaultInterval, "Time between scans for changed files by --text-index, when directories cannot be watched")
+	flags.String("size-units", string(files.SizeUnitsSI), "Units of sizes in directory listings: si (kB, MB) or binary (KiB, MiB)")
 
 	// HTTPS
 	sslFlag := flags.BoolP("ssl", "s", false, "Use HTTPS server")
@@ -49,12 +76,54 @@ func init() {
 	flags.String("sslcert", "", "Path to a full certificate file")
 	flags.String("sslkey", "", "Path to a private key file")
 	rootCmd.MarkFlagsRequiredTogether("sslcert", "sslkey")
+	flags.String("http", "", "Also serve plain HTTP on this address (host:port) while HTTPS is enabled")
+	flags.Int("http-redirect", 0, "Redirect plain HTTP requests to HTTPS with this status code (e.g. 301 or 308). Always 308 with --client-ca")
+	flags.Bool("http3", false, "Also serve HTTP/3 (QUIC) on the same address while HTTPS is enabled")
+	flags.Bool("h2c", false, "Accept HTTP/2 without TLS (h2c) on plain HTTP listeners")
+	flags.Duration("hsts", 0, "Set Strict-Transport-Security header with this max age on HTTPS responses (e.g. 8760h)")
+	flags.StringArray("tls", nil, "Certificate for a hostname, selected by SNI. Implies --ssl.\nFormat: host=cert,key (can be repeated)")
+
+	// TLS policy
+	flags.String("tls-min", "", "Minimum TLS version (e.g. 1.2, 1.3)")
+	flags.String("tls-max", "", "Maximum TLS version (e.g. 1.2, 1.3)")
+	flags.StringSlice("tls-ciphers", nil, "Allowed cipher suites for TLS 1.2 and below, in Go names (e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256)")
+	flags.StringSlice("tls-curves", nil, "Key exchange curves in order of preference (e.g. X25519MLKEM768,X25519,P256)")
+	flags.StringSlice("tls-alpn", server.DefaultNextProtos, "ALPN protocols to advertise")
+	flags.Bool("tls-no-session-tickets", false, "Disable TLS session ticket resumption")
+	flags.Duration("tls-ticket-rotation", 0, "Rotate session ticket keys at this interval (e.g. 24h)")
+
+	// Client certificates
+	flags.String("client-ca", "", "Path to CA certificates to verify client certificates with. Implies --ssl")
+	flags.String("client-auth", server.ClientAuthRequire, "Client certificate verification mode.\nAvailable modes: "+server.ClientAuthRequire+", "+server.ClientAuthOptional)
+	flags.StringArray("client-rule", nil, "Only allow clients with matching certificate CN or SAN to access a path.\nFormat: /path=name[,name...] (can be repeated, names can be glob patterns)")
+
+	// ACME
+	flags.Bool("acme", false, "Obtain and renew certificates via ACME. Implies --ssl")
+	flags.StringSlice("acme-domain", nil, "Domain to request a certificate for (can be repeated)")
+	flags.String("acme-email", "", "Contact email for the ACME account")
+	flags.String("acme-directory", acme.DefaultDirectoryURL, "ACME directory URL")
+	flags.String("acme-challenge", acme.ChallengeHTTP01, "ACME challenge type.\nAvailable types: "+acme.ChallengeHTTP01+", "+acme.ChallengeTLSALPN01)
+	flags.String("acme-http-addr", server.DefaultAcmeHTTPAddr, "Address to answer "+acme.ChallengeHTTP01+" challenges on")
+	flags.String("acme-cache", server.DefaultAcmeCacheDir, "Directory to store the ACME account key and certificates")
+	flags.String("acme-ca-roots", "", "PEM file of extra CA certificates to trust for the ACME directory")
 
 	// Proxy
 	flags.StringP("proxy", "p", "", "Proxy forward to the specified URL.\nThis will disable directory listing and file serving.")
 	flags.Bool("proxy-headers", true, "Include X-Forwarded-For and X-Forwarded-Proto headers in proxy request")
 	flags.Bool("proxy-ignore-redirect", false, "Ignore redirects from the target server")
+	flags.Bool("proxy-h2c", false, "Use HTTP/2 without TLS (h2c) to talk to the target server")
+
+	// Timeouts and limits
+	flags.Duration("read-timeout", 0, "Maximum duration to read a whole request, including the body (0 for no timeout)")
+	flags.Duration("read-header-timeout", 0, "Maximum duration to read request headers (0 for no timeout)")
+	flags.Duration("write-timeout", 0, "Maximum duration to write a response, including large downloads (0 for no timeout)")
+	flags.Duration("idle-timeout", 0, "Maximum duration to keep idle keep-alive connections open (0 for no timeout)")
+	flags.Int("max-header-bytes", 0, "Maximum size of request headers in bytes (0 for Go default of 1 MB)")
+	flags.Int("max-conns", 0, "Maximum number of connections open at once, further ones wait (0 for unlimited)")
+	flags.Duration("shutdown-timeout", server.DefaultShutdownTimeout, "Time to wait for in-flight requests when shutting down")
+	flags.Duration("drain", 0, "On SIGTERM, wait up to this long for in-flight requests such as downloads,\nthen log the ones cut off (0 to use --shutdown-timeout)")
 
 	// Other
 	flags.BoolVar(&logger.LogWithColor, "log-color", true, "Disable colored log output")
+	flags.BoolVar(&logger.Verbose, "verbose", false, "Log extra details, such as negotiated TLS parameters per connection")
 }
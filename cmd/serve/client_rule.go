@@ -0,0 +1,21 @@
+package serve
+
+import (
+	"strings"
+
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server/middlewares"
+)
+
+// Parses a "path=name[,name...]" value of the --client-rule flag
+func parseClientCertRule(value string) middlewares.ClientCertRule {
+	pathPrefix, names, found := strings.Cut(value, "=")
+	if !found || !strings.HasPrefix(pathPrefix, "/") || names == "" {
+		logger.Fatalf("Invalid --client-rule value '%s', expected format /path=name[,name...]\n", value)
+	}
+
+	return middlewares.ClientCertRule{
+		PathPrefix: pathPrefix,
+		Allowed:    strings.Split(names, ","),
+	}
+}
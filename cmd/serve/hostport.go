@@ -1,12 +1,19 @@
 package serve
 
 import (
+	"fmt"
 	"net"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
 
 	"github.com/ducng99/goserve/internal/logger"
 )
 
-func parseHostPort(hostport string) (string, string) {
+// Splits host and port, using the defaults for missing parts.
+// Returns whether the port was set in the address.
+func parseHostPort(hostport, defaultPort string) (string, string, bool) {
 	// Cannot split without a colon for port
 	// Add a colon to split then use default port
 	if !hasPort(hostport) {
@@ -19,7 +26,7 @@ func parseHostPort(hostport string) (string, string) {
 	}
 
 	host := DefaultListenHost
-	port := DefaultListenPort
+	port := defaultPort
 
 	if _host != "" {
 		host = _host
@@ -29,7 +36,7 @@ func parseHostPort(hostport string) (string, string) {
 		port = _port
 	}
 
-	return host, port
+	return host, port, _port != ""
 }
 
 func hasPort(hostport string) bool {
@@ -43,3 +50,23 @@ func hasPort(hostport string) bool {
 
 	return false
 }
+
+// Gets the port for addresses without one, and whether to fall back to the next free port
+func getPort(cmd *cobra.Command) (string, bool) {
+	port, err := cmd.Flags().GetString("port")
+	if err != nil {
+		logger.Fatalf("Error getting 'port' flag: %v\n", err)
+	}
+
+	if port == PortAuto {
+		return DefaultListenPort, true
+	}
+
+	if portNumber, err := strconv.ParseUint(port, 10, 16); err != nil || portNumber == 0 {
+		cmd.Help()
+		fmt.Printf("Invalid value for 'port' flag: %s\n", port)
+		os.Exit(1)
+	}
+
+	return port, false
+}
@@ -0,0 +1,52 @@
+package serve
+
+import (
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// Reads timeout, limit and shutdown flags into the server config
+func setLimits(cmd *cobra.Command, config *server.ServerConfig) {
+	var err error
+
+	config.ReadTimeout, err = cmd.Flags().GetDuration("read-timeout")
+	if err != nil {
+		logger.Fatalf("Error getting 'read-timeout' flag: %v\n", err)
+	}
+
+	config.ReadHeaderTimeout, err = cmd.Flags().GetDuration("read-header-timeout")
+	if err != nil {
+		logger.Fatalf("Error getting 'read-header-timeout' flag: %v\n", err)
+	}
+
+	config.WriteTimeout, err = cmd.Flags().GetDuration("write-timeout")
+	if err != nil {
+		logger.Fatalf("Error getting 'write-timeout' flag: %v\n", err)
+	}
+
+	config.IdleTimeout, err = cmd.Flags().GetDuration("idle-timeout")
+	if err != nil {
+		logger.Fatalf("Error getting 'idle-timeout' flag: %v\n", err)
+	}
+
+	config.MaxHeaderBytes, err = cmd.Flags().GetInt("max-header-bytes")
+	if err != nil {
+		logger.Fatalf("Error getting 'max-header-bytes' flag: %v\n", err)
+	}
+
+	config.MaxConns, err = cmd.Flags().GetInt("max-conns")
+	if err != nil {
+		logger.Fatalf("Error getting 'max-conns' flag: %v\n", err)
+	}
+
+	config.ShutdownTimeout, err = cmd.Flags().GetDuration("shutdown-timeout")
+	if err != nil {
+		logger.Fatalf("Error getting 'shutdown-timeout' flag: %v\n", err)
+	}
+
+	config.DrainTimeout, err = cmd.Flags().GetDuration("drain")
+	if err != nil {
+		logger.Fatalf("Error getting 'drain' flag: %v\n", err)
+	}
+}
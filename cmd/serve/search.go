@@ -0,0 +1,59 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// Reads search limit and text index flags into the server config
+func setSearchLimits(cmd *cobra.Command, config *server.ServerConfig) {
+	var err error
+
+	config.SearchMaxDepth, err = cmd.Flags().GetInt("search-depth")
+	if err != nil {
+		logger.Fatalf("Error getting 'search-depth' flag: %v\n", err)
+	}
+
+	config.SearchMaxResults, err = cmd.Flags().GetInt("search-results")
+	if err != nil {
+		logger.Fatalf("Error getting 'search-results' flag: %v\n", err)
+	}
+
+	config.SearchTimeout, err = cmd.Flags().GetDuration("search-timeout")
+	if err != nil {
+		logger.Fatalf("Error getting 'search-timeout' flag: %v\n", err)
+	}
+
+	config.TextIndex, err = cmd.Flags().GetBool("text-index")
+	if err != nil {
+		logger.Fatalf("Error getting 'text-index' flag: %v\n", err)
+	}
+
+	config.TextIndexMaxSize, err = cmd.Flags().GetInt64("text-index-max-size")
+	if err != nil {
+		logger.Fatalf("Error getting 'text-index-max-size' flag: %v\n", err)
+	}
+
+	config.TextIndexInterval, err = cmd.Flags().GetDuration("text-index-interval")
+	if err != nil {
+		logger.Fatalf("Error getting 'text-index-interval' flag: %v\n", err)
+	}
+
+	for name, valid := range map[string]bool{
+		"search-depth":        config.SearchMaxDepth > 0,
+		"search-results":      config.SearchMaxResults > 0,
+		"search-timeout":      config.SearchTimeout > 0,
+		"text-index-max-size": config.TextIndexMaxSize > 0,
+		"text-index-interval": config.TextIndexInterval > 0,
+	} {
+		if !valid {
+			cmd.Help()
+			fmt.Printf("Invalid value for '%s' flag: %s\n", name, cmd.Flags().Lookup(name).Value)
+			os.Exit(1)
+		}
+	}
+}
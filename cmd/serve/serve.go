@@ -2,32 +2,95 @@ package serve
 
 import (
 	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/spf13/cobra"
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/listener"
 	"github.com/ducng99/goserve/internal/logger"
 	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/server/middlewares"
 	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
 )
 
 const (
 	DefaultListenHost = "0.0.0.0"
 	DefaultListenPort = "8080"
+
+	// Value of the 'port' flag to use the default port, or the next free one
+	PortAuto = "auto"
 )
 
 // Default run function for root command.
 //
 // Handles flags and continue to start a server
 func HandleCommand(cmd *cobra.Command, args []string) {
+	defaultPort, portFallback := getPort(cmd)
+
 	host := DefaultListenHost
-	port := DefaultListenPort
+	port := defaultPort
+	hostPortSet := false
+
+	listenAddrs := make([]listener.Address, 0, len(args))
+	for _, arg := range args {
+		if addr, ok := listener.Parse(arg); ok {
+			listenAddrs = append(listenAddrs, addr)
+			continue
+		}
+
+		argHost, argPort, argPortSet := parseHostPort(arg, defaultPort)
+		listenAddrs = append(listenAddrs, listener.Address{
+			Network:      listener.NetworkTCP,
+			Addr:         net.JoinHostPort(argHost, argPort),
+			PortFallback: portFallback && !argPortSet,
+		})
+
+		// First TCP address is used for redirects and Alt-Svc
+		if !hostPortSet {
+			host, port = argHost, argPort
+			hostPortSet = true
+		}
+	}
+
+	socketMode := getSocketMode(cmd)
+
+	qrCode, err := cmd.Flags().GetBool("qr")
+	if err != nil {
+		logger.Fatalf("Error getting 'qr' flag: %v\n", err)
+	}
+
+	rootDirs := getRootDirs(cmd)
+
+	gitDir, err := cmd.Flags().GetString("git")
+	if err != nil {
+		logger.Fatalf("Error getting 'git' flag: %v\n", err)
+	}
+
+	gitRef, err := cmd.Flags().GetString("ref")
+	if err != nil {
+		logger.Fatalf("Error getting 'ref' flag: %v\n", err)
+	}
+
+	hideDotfiles, err := cmd.Flags().GetBool("hide-dotfiles")
+	if err != nil {
+		logger.Fatalf("Error getting 'hide-dotfiles' flag: %v\n", err)
+	}
 
-	if len(args) > 0 {
-		host, port = parseHostPort(args[0])
+	exclude, err := cmd.Flags().GetStringArray("exclude")
+	if err != nil {
+		logger.Fatalf("Error getting 'exclude' flag: %v\n", err)
 	}
 
-	rootDir := getRootDir(cmd)
+	ignoreFiles, err := cmd.Flags().GetBool("ignore-files")
+	if err != nil {
+		logger.Fatalf("Error getting 'ignore-files' flag: %v\n", err)
+	}
 
 	corsEnabled, err := cmd.Flags().GetBool("cors")
 	if err != nil {
@@ -44,6 +107,31 @@ func HandleCommand(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	indexTemplate, err := cmd.Flags().GetString("index-template")
+	if err != nil {
+		logger.Fatalf("Error getting 'index-template' flag: %v\n", err)
+	}
+
+	pageSize, err := cmd.Flags().GetInt("page-size")
+	if err != nil {
+		logger.Fatalf("Error getting 'page-size' flag: %v\n", err)
+	}
+	if pageSize < 0 || pageSize > files.MaxPerPage {
+		cmd.Help()
+		fmt.Printf("Invalid value for 'page-size' flag: %d\n", pageSize)
+		os.Exit(1)
+	}
+
+	sizeUnits, err := cmd.Flags().GetString("size-units")
+	if err != nil {
+		logger.Fatalf("Error getting 'size-units' flag: %v\n", err)
+	}
+	if !files.SizeUnits(sizeUnits).Valid() {
+		cmd.Help()
+		fmt.Printf("Invalid value for 'size-units' flag: %s\n", sizeUnits)
+		os.Exit(1)
+	}
+
 	httpsEnabled, err := cmd.Flags().GetBool("https")
 	if err != nil {
 		logger.Fatalf("Error getting 'https' flag: %v\n", err)
@@ -59,6 +147,90 @@ func HandleCommand(cmd *cobra.Command, args []string) {
 		logger.Fatalf("Error getting 'sslkey' flag: %v\n", err)
 	}
 
+	httpAddr, err := cmd.Flags().GetString("http")
+	if err != nil {
+		logger.Fatalf("Error getting 'http' flag: %v\n", err)
+	}
+
+	httpRedirect, err := cmd.Flags().GetInt("http-redirect")
+	if err != nil {
+		logger.Fatalf("Error getting 'http-redirect' flag: %v\n", err)
+	}
+	switch httpRedirect {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		cmd.Help()
+		fmt.Printf("Invalid value for 'http-redirect' flag: %d\n", httpRedirect)
+		os.Exit(1)
+	}
+
+	http3Enabled, err := cmd.Flags().GetBool("http3")
+	if err != nil {
+		logger.Fatalf("Error getting 'http3' flag: %v\n", err)
+	}
+
+	h2cEnabled, err := cmd.Flags().GetBool("h2c")
+	if err != nil {
+		logger.Fatalf("Error getting 'h2c' flag: %v\n", err)
+	}
+
+	hstsMaxAge, err := cmd.Flags().GetDuration("hsts")
+	if err != nil {
+		logger.Fatalf("Error getting 'hsts' flag: %v\n", err)
+	}
+
+	sniCertValues, err := cmd.Flags().GetStringArray("tls")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls' flag: %v\n", err)
+	}
+
+	sniCerts := make([]server.SNICert, 0, len(sniCertValues))
+	for _, value := range sniCertValues {
+		sniCerts = append(sniCerts, parseSNICert(value))
+	}
+
+	clientCAPath, err := cmd.Flags().GetString("client-ca")
+	if err != nil {
+		logger.Fatalf("Error getting 'client-ca' flag: %v\n", err)
+	}
+
+	clientAuth, err := cmd.Flags().GetString("client-auth")
+	if err != nil {
+		logger.Fatalf("Error getting 'client-auth' flag: %v\n", err)
+	}
+	if clientAuth != server.ClientAuthRequire && clientAuth != server.ClientAuthOptional {
+		cmd.Help()
+		fmt.Printf("Invalid value for 'client-auth' flag: %s\n", clientAuth)
+		os.Exit(1)
+	}
+
+	clientRuleValues, err := cmd.Flags().GetStringArray("client-rule")
+	if err != nil {
+		logger.Fatalf("Error getting 'client-rule' flag: %v\n", err)
+	}
+
+	clientCertRules := make([]middlewares.ClientCertRule, 0, len(clientRuleValues))
+	for _, value := range clientRuleValues {
+		clientCertRules = append(clientCertRules, parseClientCertRule(value))
+	}
+	if len(clientCertRules) > 0 && clientCAPath == "" {
+		cmd.Help()
+		fmt.Printf("'client-rule' requires 'client-ca' to verify client certificates\n")
+		os.Exit(1)
+	}
+
+	acmeEnabled, err := cmd.Flags().GetBool("acme")
+	if err != nil {
+		logger.Fatalf("Error getting 'acme' flag: %v\n", err)
+	}
+
+	acmeConfig, acmeHTTPAddr := getAcmeConfig(cmd)
+	if acmeEnabled && len(acmeConfig.Domains) == 0 {
+		cmd.Help()
+		fmt.Printf("At least one 'acme-domain' is required for ACME\n")
+		os.Exit(1)
+	}
+
 	proxyToAddr, err := cmd.Flags().GetString("proxy")
 	if err != nil {
 		logger.Fatalf("Error getting 'proxy' flag: %v\n", err)
@@ -74,39 +246,152 @@ func HandleCommand(cmd *cobra.Command, args []string) {
 		logger.Fatalf("Error getting 'proxy-ignore-redirect' flag: %v\n", err)
 	}
 
+	proxyH2c, err := cmd.Flags().GetBool("proxy-h2c")
+	if err != nil {
+		logger.Fatalf("Error getting 'proxy-h2c' flag: %v\n", err)
+	}
+
 	// Set up and start server
 	config := server.ServerConfig{
 		Host:                host,
 		Port:                port,
-		RootDir:             rootDir,
+		PortFallback:        portFallback,
+		ListenAddrs:         listenAddrs,
+		SocketMode:          socketMode,
+		QRCode:              qrCode,
+		RootDir:             rootDirs[0],
+		OverlayDirs:         rootDirs[1:],
+		GitDir:              gitDir,
+		GitRef:              gitRef,
+		HideDotfiles:        hideDotfiles,
+		Exclude:             exclude,
+		IgnoreFiles:         ignoreFiles,
 		CorsEnabled:         corsEnabled,
 		DirViewTheme:        dirViewTheme,
+		IndexTemplate:       indexTemplate,
+		SizeUnits:           files.SizeUnits(sizeUnits),
+		PageSize:            pageSize,
 		HttpsEnabled:        httpsEnabled,
 		CertPath:            sslCert,
 		KeyPath:             sslKey,
+		SNICerts:            sniCerts,
+		HttpAddr:            httpAddr,
+		HttpRedirect:        httpRedirect,
+		HstsMaxAge:          hstsMaxAge,
+		Http3Enabled:        http3Enabled,
+		H2cEnabled:          h2cEnabled,
+		AcmeEnabled:         acmeEnabled,
+		Acme:                acmeConfig,
+		AcmeHTTPAddr:        acmeHTTPAddr,
+		ClientCAPath:        clientCAPath,
+		ClientAuth:          clientAuth,
+		ClientCertRules:     clientCertRules,
 		ProxyToAddr:         proxyToAddr,
 		ProxyHeadersEnabled: proxyHeadersEnabled,
 		ProxyIgnoreRedirect: proxyIgnoreRedirect,
+		ProxyH2c:            proxyH2c,
 	}
 
+	setSymlinkPolicy(cmd, &config)
+	setTLSPolicy(cmd, &config)
+	setLimits(cmd, &config)
+	setSearchLimits(cmd, &config)
+
 	config.StartServer()
 }
 
-func getRootDir(cmd *cobra.Command) string {
-	userRootDir, err := cmd.Flags().GetString("dir")
+func getAcmeConfig(cmd *cobra.Command) (acme.Config, string) {
+	domains, err := cmd.Flags().GetStringSlice("acme-domain")
 	if err != nil {
-		logger.Fatalf("Error getting flag: %v\n", err)
+		logger.Fatalf("Error getting 'acme-domain' flag: %v\n", err)
+	}
+
+	email, err := cmd.Flags().GetString("acme-email")
+	if err != nil {
+		logger.Fatalf("Error getting 'acme-email' flag: %v\n", err)
+	}
+
+	directoryURL, err := cmd.Flags().GetString("acme-directory")
+	if err != nil {
+		logger.Fatalf("Error getting 'acme-directory' flag: %v\n", err)
 	}
 
-	userRootDir, err = filepath.EvalSymlinks(filepath.Clean(userRootDir))
+	challenge, err := cmd.Flags().GetString("acme-challenge")
 	if err != nil {
-		logger.Fatalf("Error resolving directory: %v\n", err)
+		logger.Fatalf("Error getting 'acme-challenge' flag: %v\n", err)
 	}
 
-	userRootDir, err = filepath.Abs(userRootDir)
+	httpAddr, err := cmd.Flags().GetString("acme-http-addr")
 	if err != nil {
-		logger.Fatalf("Error getting absolute path: %v\n", err)
+		logger.Fatalf("Error getting 'acme-http-addr' flag: %v\n", err)
+	}
+
+	cacheDir, err := cmd.Flags().GetString("acme-cache")
+	if err != nil {
+		logger.Fatalf("Error getting 'acme-cache' flag: %v\n", err)
+	}
+
+	caRootsPath, err := cmd.Flags().GetString("acme-ca-roots")
+	if err != nil {
+		logger.Fatalf("Error getting 'acme-ca-roots' flag: %v\n", err)
+	}
+
+	config := acme.Config{
+		DirectoryURL: directoryURL,
+		Email:        email,
+		Domains:      domains,
+		Challenge:    challenge,
+		CacheDir:     cacheDir,
+		CARootsPath:  caRootsPath,
+	}
+
+	return config, httpAddr
+}
+
+func getSocketMode(cmd *cobra.Command) fs.FileMode {
+	socketModeValue, err := cmd.Flags().GetString("socket-mode")
+	if err != nil {
+		logger.Fatalf("Error getting 'socket-mode' flag: %v\n", err)
+	}
+
+	if socketModeValue == "" {
+		return 0
+	}
+
+	socketMode, err := strconv.ParseUint(socketModeValue, 8, 32)
+	if err != nil || socketMode > 0777 {
+		cmd.Help()
+		fmt.Printf("Invalid value for 'socket-mode' flag: %s\n", socketModeValue)
+		os.Exit(1)
+	}
+
+	return fs.FileMode(socketMode)
+}
+
+func getRootDirs(cmd *cobra.Command) []string {
+	userRootDirs, err := cmd.Flags().GetStringArray("dir")
+	if err != nil {
+		logger.Fatalf("Error getting flag: %v\n", err)
+	}
+
+	if len(userRootDirs) == 0 {
+		userRootDirs = []string{"."}
+	}
+
+	rootDirs := make([]string, 0, len(userRootDirs))
+	for _, userRootDir := range userRootDirs {
+		userRootDir, err = filepath.EvalSymlinks(filepath.Clean(userRootDir))
+		if err != nil {
+			logger.Fatalf("Error resolving directory: %v\n", err)
+		}
+
+		userRootDir, err = filepath.Abs(userRootDir)
+		if err != nil {
+			logger.Fatalf("Error getting absolute path: %v\n", err)
+		}
+
+		rootDirs = append(rootDirs, userRootDir)
 	}
 
-	return userRootDir
+	return rootDirs
 }
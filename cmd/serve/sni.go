@@ -0,0 +1,27 @@
+package serve
+
+import (
+	"strings"
+
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+// Parses a "host=cert,key" value of the --tls flag
+func parseSNICert(value string) server.SNICert {
+	host, paths, found := strings.Cut(value, "=")
+	if !found || host == "" {
+		logger.Fatalf("Invalid --tls value '%s', expected format host=cert,key\n", value)
+	}
+
+	certPath, keyPath, found := strings.Cut(paths, ",")
+	if !found || certPath == "" || keyPath == "" {
+		logger.Fatalf("Invalid --tls value '%s', expected format host=cert,key\n", value)
+	}
+
+	return server.SNICert{
+		Host:     host,
+		CertPath: certPath,
+		KeyPath:  keyPath,
+	}
+}
@@ -0,0 +1,50 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// Reads symlink policy flags into the server config
+func setSymlinkPolicy(cmd *cobra.Command, config *server.ServerConfig) {
+	policy, err := cmd.Flags().GetString("symlinks")
+	if err != nil {
+		logger.Fatalf("Error getting 'symlinks' flag: %v\n", err)
+	}
+
+	config.Symlinks = files.SymlinkPolicy(policy)
+	if !config.Symlinks.Valid() {
+		cmd.Help()
+		fmt.Printf("Invalid value for 'symlinks' flag: %s\n", policy)
+		os.Exit(1)
+	}
+
+	allowDirs, err := cmd.Flags().GetStringArray("symlink-allow")
+	if err != nil {
+		logger.Fatalf("Error getting 'symlink-allow' flag: %v\n", err)
+	}
+
+	if len(allowDirs) > 0 && !cmd.Flags().Changed("symlinks") {
+		config.Symlinks = files.SymlinksAllowList
+	}
+
+	for _, allowDir := range allowDirs {
+		allowDir, err = filepath.EvalSymlinks(filepath.Clean(allowDir))
+		if err != nil {
+			logger.Fatalf("Error resolving directory: %v\n", err)
+		}
+
+		allowDir, err = filepath.Abs(allowDir)
+		if err != nil {
+			logger.Fatalf("Error getting absolute path: %v\n", err)
+		}
+
+		config.SymlinkAllowDirs = append(config.SymlinkAllowDirs, allowDir)
+	}
+}
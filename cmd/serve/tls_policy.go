@@ -0,0 +1,70 @@
+package serve
+
+import (
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/ssl"
+	"github.com/spf13/cobra"
+)
+
+// Reads TLS policy flags into the server config
+func setTLSPolicy(cmd *cobra.Command, config *server.ServerConfig) {
+	minVersion, err := cmd.Flags().GetString("tls-min")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-min' flag: %v\n", err)
+	}
+
+	config.TLSMinVersion, err = ssl.ParseVersion(minVersion)
+	if err != nil {
+		logger.Fatalf("Invalid value for 'tls-min' flag: %v\n", err)
+	}
+
+	maxVersion, err := cmd.Flags().GetString("tls-max")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-max' flag: %v\n", err)
+	}
+
+	config.TLSMaxVersion, err = ssl.ParseVersion(maxVersion)
+	if err != nil {
+		logger.Fatalf("Invalid value for 'tls-max' flag: %v\n", err)
+	}
+
+	cipherSuites, err := cmd.Flags().GetStringSlice("tls-ciphers")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-ciphers' flag: %v\n", err)
+	}
+
+	if len(cipherSuites) > 0 {
+		config.TLSCipherSuites, err = ssl.ParseCipherSuites(cipherSuites)
+		if err != nil {
+			logger.Fatalf("Invalid value for 'tls-ciphers' flag: %v\n", err)
+		}
+	}
+
+	curves, err := cmd.Flags().GetStringSlice("tls-curves")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-curves' flag: %v\n", err)
+	}
+
+	if len(curves) > 0 {
+		config.TLSCurves, err = ssl.ParseCurves(curves)
+		if err != nil {
+			logger.Fatalf("Invalid value for 'tls-curves' flag: %v\n", err)
+		}
+	}
+
+	config.TLSNextProtos, err = cmd.Flags().GetStringSlice("tls-alpn")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-alpn' flag: %v\n", err)
+	}
+
+	config.TLSSessionTicketsDisabled, err = cmd.Flags().GetBool("tls-no-session-tickets")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-no-session-tickets' flag: %v\n", err)
+	}
+
+	config.TLSSessionTicketRotation, err = cmd.Flags().GetDuration("tls-ticket-rotation")
+	if err != nil {
+		logger.Fatalf("Error getting 'tls-ticket-rotation' flag: %v\n", err)
+	}
+}
@@ -4,10 +4,24 @@ go 1.25
 
 require (
 	github.com/a-h/templ v0.3.960
+	github.com/alecthomas/chroma/v2 v2.27.0
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/mdp/qrterminal/v3 v3.2.1
+	github.com/quic-go/quic-go v0.57.1
 	github.com/spf13/cobra v1.10.2
+	github.com/yuin/goldmark v1.8.6
+	golang.org/x/crypto v0.45.0
+	golang.org/x/image v0.33.0
+	golang.org/x/net v0.47.0
 )
 
 require (
+	github.com/dlclark/regexp2/v2 v2.2.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/quic-go/qpack v0.6.0 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/term v0.37.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	rsc.io/qr v0.2.0 // indirect
 )
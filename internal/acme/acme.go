@@ -0,0 +1,528 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/ssl"
+	xacme "golang.org/x/crypto/acme"
+)
+
+const (
+	ChallengeHTTP01    = "http-01"
+	ChallengeTLSALPN01 = "tls-alpn-01"
+
+	// ALPN protocol used by the TLS-ALPN-01 challenge
+	ALPNProto = xacme.ALPNProto
+
+	DefaultDirectoryURL = xacme.LetsEncryptURL
+	DefaultRenewBefore  = 30 * 24 * time.Hour
+
+	accountKeyFileName = "account.key"
+	// Longest certificate file name made of the domains, longer ones are shortened with a hash
+	maxCertFileNameLength = 200
+	renewCheckInterval    = 12 * time.Hour
+	retryInterval         = 5 * time.Minute
+)
+
+var ErrNoChallenge = errors.New("no supported challenge offered by the ACME server")
+
+type Config struct {
+	// URL of the ACME directory, e.g. https://localhost:14000/dir for Pebble
+	DirectoryURL string
+	// Contact email for the ACME account, optional
+	Email string
+	// Domains to request a certificate for. All domains are added to a single certificate
+	Domains []string
+	// Challenge type to solve, either [ChallengeHTTP01] or [ChallengeTLSALPN01]
+	Challenge string
+	// Directory to store the account key and issued certificates
+	CacheDir string
+	// PEM file of extra CA certificates to trust when connecting to the ACME server
+	CARootsPath string
+	// Renew the certificate when it expires within this duration
+	RenewBefore time.Duration
+}
+
+// Obtains and renews certificates via ACME, putting them in a [ssl.CertStore]
+type Manager struct {
+	config Config
+	store  *ssl.CertStore
+
+	clientMu sync.Mutex
+	client   *xacme.Client
+
+	mu          sync.RWMutex
+	httpTokens  map[string]string
+	alpnCerts   map[string]*tls.Certificate
+	currentCert *tls.Certificate
+}
+
+func New(config Config, store *ssl.CertStore) (*Manager, error) {
+	if len(config.Domains) == 0 {
+		return nil, errors.New("at least one domain is required for ACME")
+	}
+
+	switch config.Challenge {
+	case "":
+		config.Challenge = ChallengeHTTP01
+	case ChallengeHTTP01, ChallengeTLSALPN01:
+	default:
+		return nil, fmt.Errorf("unsupported ACME challenge type '%s'", config.Challenge)
+	}
+
+	if config.DirectoryURL == "" {
+		config.DirectoryURL = DefaultDirectoryURL
+	}
+
+	if config.RenewBefore <= 0 {
+		config.RenewBefore = DefaultRenewBefore
+	}
+
+	return &Manager{
+		config:     config,
+		store:      store,
+		httpTokens: make(map[string]string),
+		alpnCerts:  make(map[string]*tls.Certificate),
+	}, nil
+}
+
+// Returns the challenge type used by this manager
+func (m *Manager) Challenge() string {
+	return m.config.Challenge
+}
+
+// Loads a previously issued certificate from disk, then keeps obtaining and renewing
+// the certificate in the background until ctx is done.
+//
+// Challenges are solved by the running server, so this should be called once it is listening.
+func (m *Manager) Start(ctx context.Context) {
+	cert, err := m.loadCert()
+	if err == nil {
+		m.setCert(cert)
+		logger.Printf(logger.LogNormal, "Using ACME certificate from disk, expires %s\n", cert.Leaf.NotAfter.Format(time.RFC1123))
+	}
+
+	go m.renewLoop(ctx)
+}
+
+// Serves HTTP-01 challenge responses, passing every other request to next
+func (m *Manager) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+		if !found {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.mu.RLock()
+		keyAuth, ok := m.httpTokens[token]
+		m.mu.RUnlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+}
+
+// Answers TLS-ALPN-01 challenges, otherwise selects a certificate from the store.
+// To be used as [crypto/tls.Config.GetCertificate]
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == ALPNProto {
+		m.mu.RLock()
+		cert, ok := m.alpnCerts[strings.ToLower(hello.ServerName)]
+		m.mu.RUnlock()
+
+		if !ok {
+			return nil, fmt.Errorf("no TLS-ALPN-01 challenge pending for '%s'", hello.ServerName)
+		}
+
+		return cert, nil
+	}
+
+	return m.store.GetCertificate(hello)
+}
+
+func (m *Manager) renewLoop(ctx context.Context) {
+	for {
+		wait := m.untilRenewal()
+
+		if m.needsRenewal() {
+			logger.Printf(logger.LogNormal, "Requesting ACME certificate for %s\n", strings.Join(m.config.Domains, ", "))
+
+			if err := m.obtain(ctx); err != nil {
+				logger.Printf(logger.LogError, "Error obtaining ACME certificate: %v\n", err)
+				wait = retryInterval
+			} else if wait = m.untilRenewal(); wait == 0 {
+				// Issued for less than RenewBefore, do not ask again right away
+				wait = retryInterval
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (m *Manager) needsRenewal() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.currentCert == nil {
+		return true
+	}
+
+	return time.Until(m.currentCert.Leaf.NotAfter) < m.config.RenewBefore
+}
+
+// Gets the time until the certificate is due for renewal, checked again at least every renewCheckInterval
+func (m *Manager) untilRenewal() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.currentCert == nil {
+		return 0
+	}
+
+	due := time.Until(m.currentCert.Leaf.NotAfter.Add(-m.config.RenewBefore))
+
+	return min(max(due, 0), renewCheckInterval)
+}
+
+// Puts the certificate in the store for all configured domains, replacing the previous one
+func (m *Manager) setCert(cert *tls.Certificate) {
+	m.mu.Lock()
+	m.currentCert = cert
+	m.mu.Unlock()
+
+	for _, domain := range m.config.Domains {
+		m.store.Set(domain, cert)
+	}
+}
+
+// Gets the ACME client, registering the account on first successful use
+func (m *Manager) getClient(ctx context.Context) (*xacme.Client, error) {
+	m.clientMu.Lock()
+	defer m.clientMu.Unlock()
+
+	if m.client == nil {
+		client, err := m.newClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		m.client = client
+	}
+
+	return m.client, nil
+}
+
+func (m *Manager) newClient(ctx context.Context) (*xacme.Client, error) {
+	accountKey, err := m.loadAccountKey()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &xacme.Client{
+		Key:          accountKey,
+		DirectoryURL: m.config.DirectoryURL,
+		UserAgent:    "goserve",
+	}
+
+	if m.config.CARootsPath != "" {
+		httpClient, err := newHTTPClient(m.config.CARootsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		client.HTTPClient = httpClient
+	}
+
+	account := &xacme.Account{}
+	if m.config.Email != "" {
+		account.Contact = []string{"mailto:" + m.config.Email}
+	}
+
+	_, err = client.Register(ctx, account, xacme.AcceptTOS)
+	if err != nil && !errors.Is(err, xacme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("error registering ACME account: %w", err)
+	}
+
+	return client, nil
+}
+
+// Runs through an ACME order for the configured domains and stores the issued certificate
+func (m *Manager) obtain(ctx context.Context) error {
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, xacme.DomainIDs(m.config.Domains...))
+	if err != nil {
+		return fmt.Errorf("error creating ACME order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := m.authorize(ctx, client, authzURL); err != nil {
+			return err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("error waiting for ACME order: %w", err)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.config.Domains[0]},
+		DNSNames: m.config.Domains,
+	}, certKey)
+	if err != nil {
+		return err
+	}
+
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("error finalising ACME order: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return err
+	}
+
+	cert := &tls.Certificate{
+		Certificate: der,
+		PrivateKey:  certKey,
+		Leaf:        leaf,
+	}
+
+	if err := m.saveCert(cert); err != nil {
+		logger.Printf(logger.LogError, "Error saving ACME certificate: %v\n", err)
+	}
+
+	m.setCert(cert)
+	logger.Printf(logger.LogSuccess, "Obtained ACME certificate for %s, expires %s\n", strings.Join(m.config.Domains, ", "), leaf.NotAfter.Format(time.RFC1123))
+
+	return nil
+}
+
+// Solves the configured challenge for a single authorization
+func (m *Manager) authorize(ctx context.Context, client *xacme.Client, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("error getting ACME authorization: %w", err)
+	}
+
+	if authz.Status == xacme.StatusValid {
+		return nil
+	}
+
+	var challenge *xacme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == m.config.Challenge {
+			challenge = c
+			break
+		}
+	}
+
+	if challenge == nil {
+		return fmt.Errorf("%w (%s for '%s')", ErrNoChallenge, m.config.Challenge, authz.Identifier.Value)
+	}
+
+	domain := strings.ToLower(authz.Identifier.Value)
+
+	switch challenge.Type {
+	case ChallengeHTTP01:
+		keyAuth, err := client.HTTP01ChallengeResponse(challenge.Token)
+		if err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		m.httpTokens[challenge.Token] = keyAuth
+		m.mu.Unlock()
+
+		defer func() {
+			m.mu.Lock()
+			delete(m.httpTokens, challenge.Token)
+			m.mu.Unlock()
+		}()
+	case ChallengeTLSALPN01:
+		cert, err := client.TLSALPN01ChallengeCert(challenge.Token, domain)
+		if err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		m.alpnCerts[domain] = &cert
+		m.mu.Unlock()
+
+		defer func() {
+			m.mu.Lock()
+			delete(m.alpnCerts, domain)
+			m.mu.Unlock()
+		}()
+	}
+
+	if _, err := client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("error accepting ACME challenge for '%s': %w", domain, err)
+	}
+
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("ACME authorization for '%s' failed: %w", domain, err)
+	}
+
+	return nil
+}
+
+// Loads the account key from the cache directory, generating a new one if it does not exist
+func (m *Manager) loadAccountKey() (crypto.Signer, error) {
+	keyPath := filepath.Join(m.config.CacheDir, accountKeyFileName)
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err == nil {
+		block, _ := pem.Decode(keyPEM)
+		if block == nil {
+			return nil, fmt.Errorf("invalid ACME account key '%s'", keyPath)
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(m.config.CacheDir, fs.ModeDir|0700); err != nil {
+		return nil, err
+	}
+
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Paths of the certificate and private key files for the configured domains.
+// Files are named after the sorted domains, e.g. "example.com+www.example.com.crt",
+// so changing the domains requests a new certificate rather than loading one missing some of them.
+func (m *Manager) certPaths() (string, string) {
+	domains := make([]string, 0, len(m.config.Domains))
+	for _, domain := range m.config.Domains {
+		domains = append(domains, strings.ReplaceAll(strings.ToLower(domain), "*", "_"))
+	}
+	slices.Sort(domains)
+	domains = slices.Compact(domains)
+
+	name := strings.Join(domains, "+")
+	if len(name) > maxCertFileNameLength {
+		sum := sha256.Sum256([]byte(name))
+		name = domains[0] + "+" + hex.EncodeToString(sum[:8])
+	}
+
+	return filepath.Join(m.config.CacheDir, name+".crt"), filepath.Join(m.config.CacheDir, name+".key")
+}
+
+func (m *Manager) loadCert() (*tls.Certificate, error) {
+	certPath, keyPath := m.certPaths()
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+func (m *Manager) saveCert(cert *tls.Certificate) error {
+	if err := os.MkdirAll(m.config.CacheDir, fs.ModeDir|0700); err != nil {
+		return err
+	}
+
+	certPEM := make([]byte, 0)
+	for _, der := range cert.Certificate {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		return err
+	}
+
+	certPath, keyPath := m.certPaths()
+
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+// Creates a HTTP client trusting the system CAs and extra CAs in the given PEM file.
+// Needed for test ACME servers like Pebble, which use their own CA for the directory.
+func newHTTPClient(caRootsPath string) (*http.Client, error) {
+	caPEM, err := os.ReadFile(caRootsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+
+	if !rootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in '%s'", caRootsPath)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,389 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedArchive = errors.New("unsupported archive format")
+
+// Serves the content of an archive as an [io/fs.FS], without extracting it.
+// Directories missing from the archive are implied by the paths of their files.
+type ArchiveFS struct {
+	entries fileIndex
+	file    *os.File
+}
+
+// Entries of a file system read from an index, e.g. the headers of an archive, by path
+type fileIndex map[string]*archiveEntry
+
+type archiveEntry struct {
+	info     fs.FileInfo
+	children []*archiveEntry
+	// Opens the content of a file, nil for directories
+	open func() (io.ReadSeeker, error)
+}
+
+// Checks whether the path looks like an archive [OpenArchive] supports, by its extension
+func IsArchive(archivePath string) bool {
+	_, ok := archiveKind(archivePath)
+	return ok
+}
+
+// Opens a .zip, .tar, .tar.gz or .tgz archive.
+// The archive is indexed once, file contents are read from it on demand.
+func OpenArchive(archivePath string) (*ArchiveFS, error) {
+	kind, ok := archiveKind(archivePath)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedArchive, archivePath)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	archive := &ArchiveFS{
+		entries: newFileIndex(info.ModTime()),
+		file:    file,
+	}
+
+	switch kind {
+	case ".zip":
+		err = archive.indexZip(info.Size())
+	case ".tar":
+		err = archive.indexTar()
+	default:
+		err = archive.indexTarGz(archivePath)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("cannot read archive '%s': %w", archivePath, err)
+	}
+
+	return archive, nil
+}
+
+func archiveKind(archivePath string) (string, bool) {
+	lowerPath := strings.ToLower(archivePath)
+
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lowerPath, ext) {
+			return ext, true
+		}
+	}
+
+	return "", false
+}
+
+// Closes the archive file
+func (a *ArchiveFS) Close() error {
+	return a.file.Close()
+}
+
+func (a *ArchiveFS) Open(name string) (fs.File, error) {
+	return a.entries.open(name)
+}
+
+func (a *ArchiveFS) add(name string, info fs.FileInfo, open func() (io.ReadSeeker, error)) {
+	a.entries.add(name, info, open)
+}
+
+func newFileIndex(rootModTime time.Time) fileIndex {
+	return fileIndex{
+		".": {info: dirInfo{name: ".", modTime: rootModTime}},
+	}
+}
+
+func (idx fileIndex) open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entry, ok := idx[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if entry.open == nil {
+		return &archiveDir{entry: entry}, nil
+	}
+
+	content, err := entry.open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &archiveFile{ReadSeeker: content, info: entry.info}, nil
+}
+
+// Adds an entry with its parent directories
+func (idx fileIndex) add(name string, info fs.FileInfo, open func() (io.ReadSeeker, error)) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" || !fs.ValidPath(name) {
+		return
+	}
+
+	if existing, ok := idx[name]; ok {
+		// A directory header after its files replaces the implied directory
+		if existing.open == nil && open == nil {
+			existing.info = namedInfo{FileInfo: info, name: path.Base(name)}
+		}
+
+		return
+	}
+
+	entry := &archiveEntry{info: namedInfo{FileInfo: info, name: path.Base(name)}, open: open}
+	idx[name] = entry
+
+	parentName := path.Dir(name)
+	parent, ok := idx[parentName]
+	if !ok {
+		idx.add(parentName, dirInfo{name: path.Base(parentName), modTime: info.ModTime()}, nil)
+		parent = idx[parentName]
+	}
+
+	// Keep children sorted, so directories can be read concurrently
+	i, _ := slices.BinarySearchFunc(parent.children, entry.info.Name(), func(child *archiveEntry, name string) int {
+		return strings.Compare(child.info.Name(), name)
+	})
+	parent.children = slices.Insert(parent.children, i, entry)
+}
+
+func (a *ArchiveFS) indexZip(size int64) error {
+	reader, err := zip.NewReader(a.file, size)
+	if err != nil {
+		return err
+	}
+
+	for _, zipFile := range reader.File {
+		info := zipFile.FileInfo()
+
+		if info.IsDir() {
+			a.add(zipFile.Name, info, nil)
+			continue
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		a.add(zipFile.Name, info, a.zipOpener(zipFile))
+	}
+
+	return nil
+}
+
+func (a *ArchiveFS) zipOpener(zipFile *zip.File) func() (io.ReadSeeker, error) {
+	size := int64(zipFile.UncompressedSize64)
+
+	// Stored files can be read in place, compressed ones have to be decompressed from the start
+	if zipFile.Method == zip.Store {
+		return func() (io.ReadSeeker, error) {
+			offset, err := zipFile.DataOffset()
+			if err != nil {
+				return nil, err
+			}
+
+			return io.NewSectionReader(a.file, offset, size), nil
+		}
+	}
+
+	return func() (io.ReadSeeker, error) {
+		return newReopenReader(zipFile.Open, size), nil
+	}
+}
+
+func (a *ArchiveFS) indexTar() error {
+	reader := tar.NewReader(a.file)
+
+	return indexTarEntries(reader, func() (int64, error) {
+		// The reader skips file contents by seeking, so the file position is where the contents start
+		return a.file.Seek(0, io.SeekCurrent)
+	}, func(offset, size int64) func() (io.ReadSeeker, error) {
+		return func() (io.ReadSeeker, error) {
+			return io.NewSectionReader(a.file, offset, size), nil
+		}
+	}, a.add)
+}
+
+func (a *ArchiveFS) indexTarGz(archivePath string) error {
+	gzipReader, err := gzip.NewReader(a.file)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	counter := &countingReader{Reader: gzipReader}
+	reader := tar.NewReader(counter)
+
+	return indexTarEntries(reader, func() (int64, error) {
+		return counter.count, nil
+	}, func(offset, size int64) func() (io.ReadSeeker, error) {
+		return func() (io.ReadSeeker, error) {
+			return newReopenReader(func() (io.ReadCloser, error) {
+				return openTarGzEntry(archivePath, offset, size)
+			}, size), nil
+		}
+	}, a.add)
+}
+
+// Adds directories and regular files of a tar archive.
+// offset returns where the contents of the current file start, opener creates the opener for them.
+func indexTarEntries(
+	reader *tar.Reader,
+	offset func() (int64, error),
+	opener func(offset, size int64) func() (io.ReadSeeker, error),
+	add func(name string, info fs.FileInfo, open func() (io.ReadSeeker, error)),
+) error {
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			add(header.Name, header.FileInfo(), nil)
+		case tar.TypeReg:
+			contentOffset, err := offset()
+			if err != nil {
+				return err
+			}
+
+			add(header.Name, header.FileInfo(), opener(contentOffset, header.Size))
+		}
+	}
+}
+
+// Decompresses a .tar.gz archive up to the contents of a file
+func openTarGzEntry(archivePath string, offset, size int64) (io.ReadCloser, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := io.CopyN(io.Discard, gzipReader, offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(gzipReader, size), file}, nil
+}
+
+type countingReader struct {
+	io.Reader
+	count int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count += int64(n)
+
+	return n, err
+}
+
+type archiveFile struct {
+	io.ReadSeeker
+	info fs.FileInfo
+}
+
+func (f *archiveFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *archiveFile) Close() error {
+	if closer, ok := f.ReadSeeker.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+type archiveDir struct {
+	entry  *archiveEntry
+	offset int
+}
+
+func (d *archiveDir) Stat() (fs.FileInfo, error) {
+	return d.entry.info, nil
+}
+
+func (d *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *archiveDir) Close() error {
+	return nil
+}
+
+func (d *archiveDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := d.entry.children[d.offset:]
+	if count > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > 0 && count < len(remaining) {
+		remaining = remaining[:count]
+	}
+	d.offset += len(remaining)
+
+	entries := make([]fs.DirEntry, 0, len(remaining))
+	for _, child := range remaining {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info))
+	}
+
+	return entries, nil
+}
+
+// File info with the base name, as archive headers can have full paths
+type namedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i namedInfo) Name() string {
+	return i.name
+}
+
+// File info of a directory implied by the paths in an archive
+type dirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (i dirInfo) Name() string       { return i.name }
+func (i dirInfo) Size() int64        { return 0 }
+func (i dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i dirInfo) ModTime() time.Time { return i.modTime }
+func (i dirInfo) IsDir() bool        { return true }
+func (i dirInfo) Sys() any           { return nil }
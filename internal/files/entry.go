@@ -1,13 +1,24 @@
 package files
 
 import (
-	"fmt"
+	"errors"
+	"io"
 	"io/fs"
-	"slices"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type DirEntry struct {
 	fs.DirEntry
+
+	// Number of entries in a directory, set by [CountItems]
+	items   int
+	counted bool
+	// Whether the directory has more than MaxCountedItems entries
+	moreItems bool
 }
 
 // Gets the name of the entry. If the entry is a directory, a "/" is appended to the name
@@ -20,6 +31,67 @@ func (e DirEntry) Name(addSlash bool) string {
 	return fileName
 }
 
+// Gets the name of the layer the entry comes from when serving multiple roots (see [UnionFS]).
+// Returns an empty string for a single root.
+func (e DirEntry) Layer() string {
+	if layered, ok := e.DirEntry.(interface{ Layer() string }); ok {
+		return layered.Layer()
+	}
+
+	return ""
+}
+
+// Checks whether any entry comes from a layer, i.e. multiple roots are served
+func HasLayers(entries []DirEntry) bool {
+	for _, entry := range entries {
+		if entry.Layer() != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Gets the commit which last changed the entry when serving a git tree (see [GitFS]).
+// Returns nil otherwise.
+func (e DirEntry) Commit() *Commit {
+	if committed, ok := e.DirEntry.(interface{ Commit() *Commit }); ok {
+		return committed.Commit()
+	}
+
+	return nil
+}
+
+// Checks whether any entry has a last commit, i.e. a git tree is served
+func HasCommits(entries []DirEntry) bool {
+	for _, entry := range entries {
+		if entry.Commit() != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Gets the target of the entry as written in the link if it is a symlink, otherwise an empty string
+func (e DirEntry) LinkTarget() string {
+	if link, ok := e.DirEntry.(interface{ LinkTarget() string }); ok {
+		return link.LinkTarget()
+	}
+
+	return ""
+}
+
+// Checks whether the entry can be opened.
+// False for symlinks which are only shown, or lead somewhere the [SymlinkPolicy] does not allow.
+func (e DirEntry) Followable() bool {
+	if link, ok := e.DirEntry.(interface{ Followable() bool }); ok {
+		return link.Followable()
+	}
+
+	return true
+}
+
 // Gets string representation of the permissions of the entry (e.g. "drwxr-xr-x").
 // If the permissions cannot be determined, "???" is returned.
 //
@@ -38,6 +110,14 @@ func (e DirEntry) Permissions() string {
 //
 // If the entry is a directory, an empty string is returned.
 func (e DirEntry) Size() string {
+	return e.SizeIn(SizeUnitsSI)
+}
+
+// Gets the size of the entry in the provided units.
+// E.g. "1.2 kB" in SI units, "1.2 KiB" in binary units.
+//
+// If the entry is a directory, an empty string is returned.
+func (e DirEntry) SizeIn(units SizeUnits) string {
 	info, err := e.Info()
 	if err != nil {
 		return "0"
@@ -47,50 +127,179 @@ func (e DirEntry) Size() string {
 		return ""
 	}
 
-	// From https://yourbasic.org/golang/formatting-byte-size-to-human-readable-format/
+	return FormatSize(info.Size(), units)
+}
+
+// Gets the exact size of the entry, e.g. "1,234,567 bytes".
+//
+// If the entry is a directory, an empty string is returned.
+func (e DirEntry) ExactSize() string {
+	info, err := e.Info()
+	if err != nil || info.IsDir() {
+		return ""
+	}
+
 	numBytes := info.Size()
+	if numBytes == 1 {
+		return "1 byte"
+	}
 
-	const unit = 1000
-	if numBytes < unit {
-		return fmt.Sprintf("%d B", numBytes)
+	return groupDigits(numBytes) + " bytes"
+}
+
+// Gets the last modification time of the entry.
+// Returns the zero time if it cannot be determined.
+func (e DirEntry) ModTime() time.Time {
+	info, err := e.Info()
+	if err != nil {
+		return time.Time{}
 	}
 
-	div, exp := int64(unit), 0
-	for n := numBytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	return info.ModTime()
+}
+
+// Gets the last modification time of the entry relative to now, e.g. "3 hours ago".
+// Returns an empty string if it cannot be determined.
+func (e DirEntry) ModTimeRelative() string {
+	modTime := e.ModTime()
+	if modTime.IsZero() {
+		return ""
+	}
+
+	return FormatRelativeTime(time.Since(modTime))
+}
+
+// Gets the last modification time of the entry in local time, e.g. "2024-01-02 15:04:05".
+// Returns an empty string if it cannot be determined.
+func (e DirEntry) ModTimeString() string {
+	modTime := e.ModTime()
+	if modTime.IsZero() {
+		return ""
+	}
+
+	return modTime.Local().Format(time.DateTime)
+}
+
+// Gets the MIME type of a file from its extension, without parameters (e.g. "text/html").
+// Returns an empty string for directories and unknown extensions.
+func (e DirEntry) MIMEType() string {
+	if e.IsDir() {
+		return ""
+	}
+
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(path.Ext(e.DirEntry.Name())), ";")
+	return mimeType
+}
+
+// Gets the name of the user owning the entry, or the user ID if it has no name.
+// Returns an empty string if the file system has no owners, e.g. archives or on Windows.
+func (e DirEntry) Owner() string {
+	info, err := e.Info()
+	if err != nil {
+		return ""
 	}
 
-	return fmt.Sprintf("%.1f %cB", float64(numBytes)/float64(div), "kMGTPE"[exp])
+	owner, _ := fileOwner(info)
+	return owner
 }
 
-// Sorts by directories first, then by name
-func Sort(entries []DirEntry) {
-	sortDir := make([]DirEntry, 0, len(entries))
-	sortFile := make([]DirEntry, 0, len(entries))
+// Gets the name of the group owning the entry, or the group ID if it has no name.
+// Returns an empty string if the file system has no owners, e.g. archives or on Windows.
+func (e DirEntry) Group() string {
+	info, err := e.Info()
+	if err != nil {
+		return ""
+	}
+
+	_, group := fileOwner(info)
+	return group
+}
 
+// Checks whether any entry has an owner, i.e. the file system records them
+func HasOwners(entries []DirEntry) bool {
 	for _, entry := range entries {
-		if entry.IsDir() {
-			sortDir = append(sortDir, entry)
-		} else {
-			sortFile = append(sortFile, entry)
+		if entry.Owner() != "" {
+			return true
 		}
 	}
 
-	sortEntry := func(a, b DirEntry) int {
-		if a.Name(false) < b.Name(false) {
-			return -1
+	return false
+}
+
+// Entries counted at most per directory by [CountItems], so huge directories do not slow down listings of their parent
+const MaxCountedItems = 1000
+
+// Gets the number of entries in a directory counted by [CountItems], or -1 if they were not counted.
+// Directories with more entries than [MaxCountedItems] count as MaxCountedItems.
+func (e DirEntry) ItemCount() int {
+	if !e.counted {
+		return -1
+	}
+
+	return e.items
+}
+
+// Gets the number of entries in a directory counted by [CountItems], e.g. "3 items".
+// Returns an empty string if they were not counted.
+func (e DirEntry) Items() string {
+	if e.moreItems {
+		return strconv.Itoa(MaxCountedItems) + "+ items"
+	}
+
+	switch count := e.ItemCount(); count {
+	case -1:
+		return ""
+	case 1:
+		return "1 item"
+	default:
+		return strconv.Itoa(count) + " items"
+	}
+}
+
+// Counts the entries of the sub-directories of dir, which entries are read from, up to [MaxCountedItems] each.
+// Sub-directories which cannot be read are left uncounted.
+func CountItems(fsys fs.FS, dir string, entries []DirEntry) {
+	for i, entry := range entries {
+		if !entry.IsDir() || !entry.Followable() {
+			continue
 		}
 
-		if a.Name(false) > b.Name(false) {
-			return 1
+		count, err := countEntries(fsys, path.Join(dir, entry.DirEntry.Name()))
+		if err != nil {
+			continue
 		}
 
-		return 0
+		entries[i].items = min(count, MaxCountedItems)
+		entries[i].moreItems = count > MaxCountedItems
+		entries[i].counted = true
+	}
+}
+
+// Counts the entries of a directory in batches, stopping once there are more than MaxCountedItems
+func countEntries(fsys fs.FS, name string) (int, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return 0, err
 	}
+	defer f.Close()
 
-	slices.SortFunc(sortDir, sortEntry)
-	slices.SortFunc(sortFile, sortEntry)
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return 0, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not implemented")}
+	}
+
+	count := 0
+	for count <= MaxCountedItems {
+		batch, err := dir.ReadDir(min(MaxCountedItems+1-count, 256))
+		count += len(batch)
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
 
-	copy(entries, append(sortDir, sortFile...))
+	return count, nil
 }
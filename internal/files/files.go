@@ -1,8 +1,6 @@
 package files
 
-import (
-	"os"
-)
+import "io/fs"
 
 type PathType uint8
 
@@ -11,10 +9,10 @@ const (
 	PathTypeDirectory
 )
 
-// Get the type of a path - file or directory.
+// Get the type of a path in a file system - file or directory.
 // If the path does not exist or inaccessible, an error is returned.
-func GetPathType(path string) (PathType, error) {
-	f, err := os.Stat(path)
+func GetPathType(fsys fs.FS, name string) (PathType, error) {
+	f, err := fs.Stat(fsys, name)
 	if err != nil {
 		return 0, err
 	}
@@ -26,17 +24,17 @@ func GetPathType(path string) (PathType, error) {
 	return PathTypeFile, nil
 }
 
-// Get a list of files and sub-directories in a directory.
-// Uses [os.ReadDir] internally
-func GetEntries(absPath string) ([]DirEntry, error) {
-	entries, err := os.ReadDir(absPath)
+// Get a list of files and sub-directories in a directory of a file system.
+// Uses [io/fs.ReadDir] internally
+func GetEntries(fsys fs.FS, name string) ([]DirEntry, error) {
+	entries, err := fs.ReadDir(fsys, name)
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]DirEntry, 0, len(entries))
 	for _, entry := range entries {
-		result = append(result, DirEntry{entry})
+		result = append(result, DirEntry{DirEntry: entry})
 	}
 
 	return result, nil
@@ -0,0 +1,241 @@
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Ignore files read in each directory when honoring them, rules in later files win
+var ignoreFileNames = []string{".gitignore", ".goserveignore"}
+
+// Paths to hide from listings and direct access
+type FilterOptions struct {
+	// Hide files and directories whose name starts with "."
+	HideDotfiles bool
+	// Patterns in .gitignore syntax, relative to the root
+	Exclude []string
+	// Hide paths ignored by .gitignore and .goserveignore files, as well as .git and .goserveignore themselves
+	IgnoreFiles bool
+}
+
+// Compiled [FilterOptions]
+type Filter struct {
+	hideDotfiles bool
+	exclude      []ignoreRule
+	ignoreFiles  bool
+}
+
+// Compiles the options, returning an error for an invalid exclude pattern
+func NewFilter(opts FilterOptions) (*Filter, error) {
+	filter := &Filter{hideDotfiles: opts.HideDotfiles, ignoreFiles: opts.IgnoreFiles}
+
+	for _, pattern := range opts.Exclude {
+		rule, ok, err := parseIgnoreRule(pattern, ".")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("empty exclude pattern")
+		}
+
+		filter.exclude = append(filter.exclude, rule)
+	}
+
+	return filter, nil
+}
+
+// Wraps a file system so hidden paths do not exist, neither in directory listings nor when opened.
+// Returns fsys as is if nothing is hidden.
+func (f *Filter) FS(fsys fs.FS) fs.FS {
+	if f == nil || (!f.hideDotfiles && len(f.exclude) == 0 && !f.ignoreFiles) {
+		return fsys
+	}
+
+	return &FilterFS{fsys: fsys, filter: f}
+}
+
+// File system with paths hidden by a [Filter]
+type FilterFS struct {
+	fsys   fs.FS
+	filter *Filter
+}
+
+func (f *FilterFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	// Hidden regardless of whether it is a directory, checked first so errors do not reveal the path
+	if f.hiddenPath(name, false) || f.hiddenTarget(name, false) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return file, nil
+	}
+
+	// Patterns only matching directories
+	if f.hiddenPath(name, true) || f.hiddenTarget(name, true) {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return &filterDir{File: file, fsys: f, name: name}, nil
+}
+
+// Checks each directory on the way to name, as everything inside a hidden directory is hidden too
+func (f *FilterFS) hiddenPath(name string, isDir bool) bool {
+	if name == "." {
+		return false
+	}
+
+	var rules []ignoreRule
+	dir := "."
+
+	components := strings.Split(name, "/")
+	for i, component := range components {
+		rules = append(rules, f.ignoreRules(dir)...)
+
+		current := path.Join(dir, component)
+		if f.hidden(rules, current, i < len(components)-1 || isDir) {
+			return true
+		}
+
+		dir = current
+	}
+
+	return false
+}
+
+// File systems telling the path a name leads to after following symlinks, like [OSFS.ResolvePath]
+type pathResolver interface {
+	ResolvePath(name string) (string, bool)
+}
+
+// Checks the path a symlink leads to, so links cannot reveal hidden files, e.g. "docs/env" linking to "../.env".
+// Only file systems resolving symlinks within their root, like [OSFS], are checked.
+func (f *FilterFS) hiddenTarget(name string, isDir bool) bool {
+	resolver, ok := f.fsys.(pathResolver)
+	if !ok {
+		return false
+	}
+
+	target, ok := resolver.ResolvePath(name)
+	if !ok || target == name {
+		return false
+	}
+
+	return f.hiddenPath(target, isDir)
+}
+
+// Checks a path against the filter, with the rules of ignore files in its parent directories
+func (f *FilterFS) hidden(rules []ignoreRule, name string, isDir bool) bool {
+	base := path.Base(name)
+
+	if f.filter.hideDotfiles && strings.HasPrefix(base, ".") {
+		return true
+	}
+
+	if ignored, _ := matchIgnoreRules(f.filter.exclude, name, isDir); ignored {
+		return true
+	}
+
+	if !f.filter.ignoreFiles {
+		return false
+	}
+
+	// Other spellings open the same files on case-insensitive file systems
+	if strings.EqualFold(base, ".git") || strings.EqualFold(base, ".goserveignore") {
+		return true
+	}
+
+	ignored, _ := matchIgnoreRules(rules, name, isDir)
+
+	return ignored
+}
+
+// Reads the ignore files in a directory, they are read on each request so changes apply right away
+func (f *FilterFS) ignoreRules(dir string) []ignoreRule {
+	if !f.filter.ignoreFiles {
+		return nil
+	}
+
+	var rules []ignoreRule
+
+	for _, fileName := range ignoreFileNames {
+		file, err := f.fsys.Open(path.Join(dir, fileName))
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, parseIgnoreFile(file, dir)...)
+		file.Close()
+	}
+
+	return rules
+}
+
+type filterDir struct {
+	fs.File
+	fsys *FilterFS
+	name string
+
+	// Rules of ignore files in this directory and its parents
+	rules []ignoreRule
+	read  bool
+}
+
+func (d *filterDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	dir, ok := d.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: errors.New("not implemented")}
+	}
+
+	if !d.read {
+		for current := d.name; ; current = path.Dir(current) {
+			d.rules = append(d.fsys.ignoreRules(current), d.rules...)
+			if current == "." {
+				break
+			}
+		}
+		d.read = true
+	}
+
+	for {
+		entries, err := dir.ReadDir(count)
+
+		visible := make([]fs.DirEntry, 0, len(entries))
+		for _, entry := range entries {
+			name := path.Join(d.name, entry.Name())
+			if d.fsys.hidden(d.rules, name, entry.IsDir()) {
+				continue
+			}
+
+			if (DirEntry{DirEntry: entry}).LinkTarget() != "" && d.fsys.hiddenTarget(name, entry.IsDir()) {
+				continue
+			}
+
+			visible = append(visible, entry)
+		}
+
+		// Keep reading when a whole batch was hidden, as an empty batch means the end of the directory
+		if count > 0 && len(visible) == 0 && err == nil {
+			continue
+		}
+
+		return visible, err
+	}
+}
@@ -0,0 +1,112 @@
+package files
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Units of human-readable sizes
+type SizeUnits string
+
+const (
+	// Powers of 1000, e.g. "1.2 kB"
+	SizeUnitsSI SizeUnits = "si"
+	// Powers of 1024, e.g. "1.2 KiB"
+	SizeUnitsBinary SizeUnits = "binary"
+)
+
+// Checks whether the input is known size units
+func (u SizeUnits) Valid() bool {
+	switch u {
+	case SizeUnitsSI, SizeUnitsBinary:
+		return true
+	default:
+		return false
+	}
+}
+
+// Formats a number of bytes in human-readable units, e.g. "1.2 kB" or "1.2 KiB".
+// Unknown units are treated as [SizeUnitsSI].
+func FormatSize(numBytes int64, units SizeUnits) string {
+	// From https://yourbasic.org/golang/formatting-byte-size-to-human-readable-format/
+	unit, prefixes, suffix := int64(1000), "kMGTPE", "B"
+	if units == SizeUnitsBinary {
+		unit, prefixes, suffix = 1024, "KMGTPE", "iB"
+	}
+
+	if numBytes < unit {
+		return fmt.Sprintf("%d B", numBytes)
+	}
+
+	div, exp := unit, 0
+	for n := numBytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %c%s", float64(numBytes)/float64(div), prefixes[exp], suffix)
+}
+
+// Formats a duration since a time in the past, e.g. "just now", "5 minutes ago" or "2 years ago".
+// Times in the future, e.g. from clock skew, are formatted as "in 5 minutes".
+func FormatRelativeTime(since time.Duration) string {
+	future := since < 0
+	if future {
+		since = -since
+	}
+
+	const (
+		day   = 24 * time.Hour
+		month = 30 * day
+		year  = 365 * day
+	)
+
+	var count int64
+	var unit string
+
+	switch {
+	case since < time.Minute:
+		return "just now"
+	case since < time.Hour:
+		count, unit = int64(since/time.Minute), "minute"
+	case since < day:
+		count, unit = int64(since/time.Hour), "hour"
+	case since < month:
+		count, unit = int64(since/day), "day"
+	case since < year:
+		count, unit = int64(since/month), "month"
+	default:
+		count, unit = int64(since/year), "year"
+	}
+
+	if count != 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", count, unit)
+	}
+
+	return fmt.Sprintf("%d %s ago", count, unit)
+}
+
+// Formats a number with thousands separators, e.g. "1,234,567"
+func groupDigits(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+
+	sign := ""
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+
+	grouped := make([]byte, 0, len(digits)+len(digits)/3)
+	for i := range len(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped = append(grouped, ',')
+		}
+		grouped = append(grouped, digits[i])
+	}
+
+	return sign + string(grouped)
+}
@@ -0,0 +1,559 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnknownRef = errors.New("unknown ref")
+
+// Number of trees kept in memory, each browsed ref needs one
+const maxCachedTrees = 8
+
+// Format of commits printed by git log and git show, parsed by parseCommit
+const commitFormat = "--format=%H%x00%an%x00%ct%x00%s"
+
+// A git repository, read from its object database with the git command rather than the working copy
+type GitRepo struct {
+	dir string
+
+	mu sync.Mutex
+	// Trees by commit hash
+	trees map[string]*GitFS
+	// Trees being read by commit hash, so concurrent requests for a commit wait for a single read
+	reading map[string]*treeRead
+}
+
+// A tree being read, done is closed once tree or err is set
+type treeRead struct {
+	done chan struct{}
+	tree *GitFS
+	err  error
+}
+
+// A commit which last changed an entry of a [GitFS]
+type Commit struct {
+	Hash    string
+	Author  string
+	Time    time.Time
+	Subject string
+}
+
+// Gets the abbreviated commit hash, e.g. "1a2b3c4"
+func (c Commit) ShortHash() string {
+	return c.Hash[:min(7, len(c.Hash))]
+}
+
+// Opens the git repository containing dir
+func OpenGitRepo(dir string) (*GitRepo, error) {
+	repo := &GitRepo{dir: dir, trees: make(map[string]*GitFS), reading: make(map[string]*treeRead)}
+
+	if _, err := repo.output("rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("cannot open git repository '%s': %w", dir, err)
+	}
+
+	return repo, nil
+}
+
+// Lists branches, then tags with the newest version first
+func (g *GitRepo) Refs() ([]string, error) {
+	branches, err := g.output("for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("cannot list branches: %w", err)
+	}
+
+	tags, err := g.output("for-each-ref", "--format=%(refname:short)", "--sort=-v:refname", "refs/tags")
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tags: %w", err)
+	}
+
+	return strings.Fields(string(branches) + string(tags)), nil
+}
+
+// Gets the hash of the commit a ref points to, or [ErrUnknownRef]
+func (g *GitRepo) Resolve(ref string) (string, error) {
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("%w: '%s'", ErrUnknownRef, ref)
+	}
+
+	hash, err := g.output("rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%w: '%s'", ErrUnknownRef, ref)
+	}
+
+	return strings.TrimSpace(string(hash)), nil
+}
+
+// Gets the tree of the commit a ref points to.
+// Branches are resolved on each call, so new commits are picked up.
+func (g *GitRepo) Tree(ref string) (*GitFS, error) {
+	hash, err := g.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+
+	if tree, ok := g.trees[hash]; ok {
+		g.mu.Unlock()
+		return tree, nil
+	}
+
+	if read, ok := g.reading[hash]; ok {
+		g.mu.Unlock()
+		<-read.done
+		return read.tree, read.err
+	}
+
+	// Trees of other commits are served while this one is read
+	read := &treeRead{done: make(chan struct{})}
+	g.reading[hash] = read
+	g.mu.Unlock()
+
+	read.tree, read.err = g.readTree(hash)
+
+	g.mu.Lock()
+	delete(g.reading, hash)
+	if read.err == nil {
+		if len(g.trees) >= maxCachedTrees {
+			for cachedHash := range g.trees {
+				delete(g.trees, cachedHash)
+				break
+			}
+		}
+		g.trees[hash] = read.tree
+	}
+	g.mu.Unlock()
+	close(read.done)
+
+	return read.tree, read.err
+}
+
+func (g *GitRepo) command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "git", append([]string{"-C", g.dir}, args...)...)
+}
+
+// Runs git, returning its output or an error with what it printed to stderr
+func (g *GitRepo) output(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := g.command(context.Background(), args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Indexes the files of a commit
+func (g *GitRepo) readTree(hash string) (*GitFS, error) {
+	out, err := g.output("show", "--no-patch", commitFormat, hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read commit %s: %w", hash, err)
+	}
+
+	commit, ok := parseCommit(strings.Split(strings.TrimSpace(string(out)), "\x00"))
+	if !ok {
+		return nil, fmt.Errorf("cannot read commit %s", hash)
+	}
+
+	tree := &GitFS{
+		repo:        g,
+		commit:      commit,
+		entries:     newFileIndex(commit.Time),
+		lastCommits: make(map[string]Commit),
+		walking:     make(map[string]chan struct{}),
+	}
+
+	out, err = g.output("ls-tree", "-r", "-t", "-z", "--long", "--full-tree", hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tree of %s: %w", hash, err)
+	}
+
+	for record := range strings.SplitSeq(string(out), "\x00") {
+		// <mode> <type> <object> <size>\t<path>
+		meta, name, ok := strings.Cut(record, "\t")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(meta)
+		if len(fields) != 4 {
+			continue
+		}
+
+		switch {
+		case fields[1] == "tree":
+			tree.entries.add(name, dirInfo{name: path.Base(name), modTime: commit.Time}, nil)
+		// Symlinks (120000) and submodules are left out
+		case fields[1] == "blob" && (fields[0] == "100644" || fields[0] == "100755"):
+			size, err := strconv.ParseInt(fields[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot list tree of %s: invalid size of '%s'", hash, name)
+			}
+
+			mode := fs.FileMode(0444)
+			if fields[0] == "100755" {
+				mode = 0555
+			}
+
+			info := blobInfo{name: path.Base(name), size: size, mode: mode, modTime: commit.Time}
+			tree.entries.add(name, info, g.blobOpener(fields[2], size))
+		}
+	}
+
+	return tree, nil
+}
+
+func (g *GitRepo) blobOpener(object string, size int64) func() (io.ReadSeeker, error) {
+	return func() (io.ReadSeeker, error) {
+		return newReopenReader(func() (io.ReadCloser, error) {
+			return g.catBlob(object)
+		}, size), nil
+	}
+}
+
+// Streams the content of a blob
+func (g *GitRepo) catBlob(object string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cmd := g.command(ctx, "cat-file", "blob", object)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &commandReader{Reader: stdout, cmd: cmd, cancel: cancel}, nil
+}
+
+// Output of a running command, closing it stops the command
+type commandReader struct {
+	io.Reader
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+}
+
+func (r *commandReader) Close() error {
+	r.cancel()
+	r.cmd.Wait()
+
+	return nil
+}
+
+func parseCommit(fields []string) (Commit, bool) {
+	if len(fields) < 4 {
+		return Commit{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Commit{}, false
+	}
+
+	return Commit{Hash: fields[0], Author: fields[1], Time: time.Unix(timestamp, 0), Subject: fields[3]}, true
+}
+
+// Files of a commit in a [GitRepo].
+// Directory entries carry the commit which last changed them, see [DirEntry.Commit].
+type GitFS struct {
+	repo    *GitRepo
+	commit  Commit
+	entries fileIndex
+
+	mu sync.Mutex
+	// Last commits by path, a zero commit if none was found
+	lastCommits map[string]Commit
+	// Directories whose history is being walked, closed when done
+	walking map[string]chan struct{}
+}
+
+// Gets the commit the files are from
+func (g *GitFS) Commit() Commit {
+	return g.commit
+}
+
+func (g *GitFS) Open(name string) (fs.File, error) {
+	f, err := g.entries.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir, ok := f.(*archiveDir); ok {
+		return &gitDir{archiveDir: dir, tree: g, name: name}, nil
+	}
+
+	return f, nil
+}
+
+// Finds the commits which last changed the entries of a directory, walking the history once.
+// Concurrent calls for a directory wait for the same walk, the lock is not held while git runs.
+func (g *GitFS) findLastCommits(dir string, names []string) (map[string]Commit, error) {
+	var found map[string]Commit
+	var missing map[string]bool
+
+	for {
+		g.mu.Lock()
+		found, missing = g.cachedLastCommits(dir, names)
+		if len(missing) == 0 {
+			g.mu.Unlock()
+			return found, nil
+		}
+
+		walking, ok := g.walking[dir]
+		if !ok {
+			break
+		}
+
+		// The other walk may have been for other entries, or failed, so look again once it is done
+		g.mu.Unlock()
+		<-walking
+	}
+
+	done := make(chan struct{})
+	g.walking[dir] = done
+	g.mu.Unlock()
+
+	err := g.walkHistory(dir, found, missing)
+
+	g.mu.Lock()
+	if err == nil {
+		for _, name := range names {
+			g.lastCommits[path.Join(dir, name)] = found[name]
+		}
+	}
+	delete(g.walking, dir)
+	g.mu.Unlock()
+	close(done)
+
+	return found, err
+}
+
+// Gets the known last commits of entries of a directory, and the entries whose last commit is not known yet.
+// The lock must be held.
+func (g *GitFS) cachedLastCommits(dir string, names []string) (map[string]Commit, map[string]bool) {
+	found := make(map[string]Commit, len(names))
+	missing := make(map[string]bool)
+
+	for _, name := range names {
+		if commit, ok := g.lastCommits[path.Join(dir, name)]; ok {
+			found[name] = commit
+		} else {
+			missing[name] = true
+		}
+	}
+
+	return found, missing
+}
+
+// Walks the history of a directory until the last commits of the missing entries are found
+func (g *GitFS) walkHistory(dir string, found map[string]Commit, missing map[string]bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	args := []string{"log", "--format=%x1e" + strings.TrimPrefix(commitFormat, "--format="), "--name-only", "-z", "--no-renames", g.commit.Hash}
+	if dir != "." {
+		// Relative to the top of the work tree, not to the directory git runs in
+		args = append(args, "--", ":(top,literal)"+dir)
+	}
+
+	var stderr bytes.Buffer
+	cmd := g.repo.command(ctx, args...)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("cannot read history of '%s': %w", dir, err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	for len(missing) > 0 {
+		record, err := reader.ReadString('\x1e')
+		if record = strings.TrimSuffix(record, "\x1e"); record != "" {
+			// <hash>\0<author>\0<time>\0<subject>\0\n<file>\0<file>\0...
+			fields := strings.Split(record, "\x00")
+
+			if commit, ok := parseCommit(fields); ok {
+				for _, file := range fields[4:] {
+					file = strings.TrimPrefix(file, "\n")
+					if dir != "." {
+						file = strings.TrimPrefix(file, dir+"/")
+					}
+
+					child, _, _ := strings.Cut(file, "/")
+					if missing[child] {
+						found[child] = commit
+						delete(missing, child)
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	// The rest of the history is not needed, git being stopped is not an error then
+	stopped := len(missing) == 0
+	cancel()
+
+	if err := cmd.Wait(); err != nil && !stopped {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("cannot read history of '%s': %w: %s", dir, err, message)
+		}
+
+		return fmt.Errorf("cannot read history of '%s': %w", dir, err)
+	}
+
+	return nil
+}
+
+type gitDir struct {
+	*archiveDir
+	tree *GitFS
+	name string
+}
+
+func (d *gitDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	entries, err := d.archiveDir.ReadDir(count)
+	if len(entries) == 0 {
+		return entries, err
+	}
+
+	// Finding the last commits walks the history, only do it if they are asked for
+	commits := &dirCommits{tree: d.tree, dir: d.name, names: make([]string, 0, len(entries))}
+	for i, entry := range entries {
+		commits.names = append(commits.names, entry.Name())
+		entries[i] = gitDirEntry{DirEntry: entry, commits: commits}
+	}
+
+	return entries, err
+}
+
+// Last commits of a batch of entries read from a directory, found on first use
+type dirCommits struct {
+	once    sync.Once
+	tree    *GitFS
+	dir     string
+	names   []string
+	commits map[string]Commit
+}
+
+func (c *dirCommits) get(name string) (Commit, bool) {
+	c.once.Do(func() {
+		// Entries are listed without commits if the history cannot be read
+		c.commits, _ = c.tree.findLastCommits(c.dir, c.names)
+	})
+
+	commit, ok := c.commits[name]
+	return commit, ok && commit.Hash != ""
+}
+
+// Directory entry of a [GitFS], with the commit which last changed it
+type gitDirEntry struct {
+	fs.DirEntry
+	commits *dirCommits
+}
+
+func (e gitDirEntry) Commit() *Commit {
+	if commit, ok := e.commits.get(e.Name()); ok {
+		return &commit
+	}
+
+	return nil
+}
+
+// Uses the time of the last commit as the modification time
+func (e gitDirEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	if commit, ok := e.commits.get(e.Name()); ok {
+		return commitInfo{FileInfo: info, commit: commit}, nil
+	}
+
+	return info, nil
+}
+
+type commitInfo struct {
+	fs.FileInfo
+	commit Commit
+}
+
+func (i commitInfo) ModTime() time.Time {
+	return i.commit.Time
+}
+
+// File info of a blob in a git tree
+type blobInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i blobInfo) Name() string       { return i.name }
+func (i blobInfo) Size() int64        { return i.size }
+func (i blobInfo) Mode() fs.FileMode  { return i.mode }
+func (i blobInfo) ModTime() time.Time { return i.modTime }
+func (i blobInfo) IsDir() bool        { return false }
+func (i blobInfo) Sys() any           { return nil }
+
+// Refs offered by the ref switcher of directory listings
+type GitRefs struct {
+	// Ref being browsed
+	Current string
+	// Ref browsed when none is requested
+	Default string
+	Refs    []string
+}
+
+// Gets the query string to keep browsing the current ref in links, empty for the default ref
+func (r *GitRefs) Query() string {
+	if r == nil || r.Current == r.Default {
+		return ""
+	}
+
+	return "?ref=" + url.QueryEscape(r.Current)
+}
+
+// Lists the refs with the current one, which may be a commit hash rather than a branch or tag
+func (r *GitRefs) Options() []string {
+	for _, ref := range r.Refs {
+		if ref == r.Current {
+			return r.Refs
+		}
+	}
+
+	return append([]string{r.Current}, r.Refs...)
+}
@@ -0,0 +1,156 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A pattern in .gitignore syntax
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	// Directory of the ignore file the rule is from, "." for the root
+	base string
+	// Matched against the path relative to base rather than the name, as the pattern contains a "/"
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// Parses a pattern in .gitignore syntax, e.g. "*.log", "/build/", "docs/**/*.tmp" or "!keep.log".
+// Returns false for blank lines and comments.
+func parseIgnoreRule(line, base string) (ignoreRule, bool, error) {
+	rule := ignoreRule{base: base}
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	if line == "" {
+		return rule, false, nil
+	}
+
+	pattern, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return rule, false, fmt.Errorf("invalid pattern '%s': %w", line, err)
+	}
+	rule.pattern = pattern
+
+	return rule, true, nil
+}
+
+// Parses an ignore file, skipping invalid patterns as git does
+func parseIgnoreFile(r io.Reader, base string) []ignoreRule {
+	var rules []ignoreRule
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if rule, ok, err := parseIgnoreRule(scanner.Text(), base); ok && err == nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// Converts a glob to a regular expression matching the whole path.
+// "*" and "?" do not match "/", "**" matches any number of directories.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				switch {
+				case strings.HasPrefix(glob[i:], "**/"):
+					sb.WriteString("(?:.*/)?")
+					i += 2
+				default:
+					sb.WriteString(".*")
+					i++
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return sb.String()
+}
+
+// Checks a path against rules, the last matching rule wins.
+// matched is false when no rule applies to the path.
+func matchIgnoreRules(rules []ignoreRule, name string, isDir bool) (ignored, matched bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		relative := name
+		if rule.base != "." {
+			if !strings.HasPrefix(name, rule.base+"/") {
+				continue
+			}
+			relative = strings.TrimPrefix(name, rule.base+"/")
+		}
+
+		if !rule.anchored {
+			relative = path.Base(relative)
+		}
+
+		if rule.pattern.MatchString(relative) {
+			return !rule.negate, true
+		}
+	}
+
+	return false, false
+}
@@ -0,0 +1,160 @@
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Serves a directory of the OS as an [io/fs.FS].
+// Unlike [os.DirFS], symlinks are treated according to a [SymlinkPolicy], by default they are refused if they lead out of the root.
+type OSFS struct {
+	// Absolute, with symlinks resolved so only symlinks under it are treated by the policy
+	root      string
+	symlinks  SymlinkPolicy
+	allowDirs []string
+}
+
+func NewOSFS(rootDir string) OSFS {
+	return OSFS{root: resolveDir(rootDir), symlinks: SymlinksWithinRoot}
+}
+
+// Sets how symlinks are treated.
+// allowDirs are the directories symlinks may lead to besides the root with [SymlinksAllowList].
+func (f OSFS) WithSymlinks(policy SymlinkPolicy, allowDirs ...string) OSFS {
+	f.symlinks = policy
+	f.allowDirs = make([]string, len(allowDirs))
+	for i, allowDir := range allowDirs {
+		f.allowDirs[i] = resolveDir(allowDir)
+	}
+	return f
+}
+
+// Gets the absolute path of a directory with symlinks resolved, as far as it can be
+func resolveDir(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		return resolved
+	}
+
+	return absDir
+}
+
+func (f OSFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	absPath, err := f.resolve(name)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorSanitiseNotExists):
+			err = fs.ErrNotExist
+		case errors.Is(err, ErrorSanitiseUnauthorized):
+			err = fs.ErrPermission
+		}
+
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &osDir{File: file, fsys: f, name: name}, nil
+	}
+
+	return file, nil
+}
+
+// Gets the absolute path to open for a name, following symlinks as the policy allows
+func (f OSFS) resolve(name string) (string, error) {
+	switch f.symlinks {
+	case SymlinksNever, SymlinksShow:
+		absPath := filepath.Join(f.root, filepath.FromSlash(name))
+
+		resolved, err := filepath.EvalSymlinks(absPath)
+		if err != nil {
+			return "", ErrorSanitiseNotExists
+		}
+
+		// A symlink somewhere in the path
+		if resolved != absPath {
+			if f.symlinks == SymlinksNever {
+				return "", ErrorSanitiseNotExists
+			}
+
+			return "", ErrorSanitiseUnauthorized
+		}
+
+		return absPath, nil
+	case SymlinksAllowList:
+		absPath, err := SanitisePath(f.root, name)
+		if !errors.Is(err, ErrorSanitiseUnauthorized) {
+			return absPath, err
+		}
+
+		resolved, err := filepath.EvalSymlinks(filepath.Join(f.root, filepath.FromSlash(name)))
+		if err != nil {
+			return "", ErrorSanitiseNotExists
+		}
+
+		for _, allowDir := range f.allowDirs {
+			if isWithin(allowDir, resolved) {
+				return resolved, nil
+			}
+		}
+
+		return "", ErrorSanitiseUnauthorized
+	default:
+		return SanitisePath(f.root, name)
+	}
+}
+
+// Gets the path within the root that name leads to after following symlinks.
+// Returns false if the path does not exist or leads out of the root.
+func (f OSFS) ResolvePath(name string) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(f.root, filepath.FromSlash(name)))
+	if err != nil || !isWithin(f.root, resolved) {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(f.root, resolved)
+	if err != nil {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
+
+// Creates the listing entry of a symlink in the directory dirName
+func (f OSFS) symlinkEntry(dirName string, entry fs.DirEntry) symlinkDirEntry {
+	name := path.Join(dirName, entry.Name())
+	linkEntry := symlinkDirEntry{DirEntry: entry}
+
+	linkEntry.target, _ = os.Readlink(filepath.Join(f.root, filepath.FromSlash(name)))
+
+	if f.symlinks == SymlinksShow {
+		return linkEntry
+	}
+
+	if absPath, err := f.resolve(name); err == nil {
+		linkEntry.targetInfo, _ = os.Stat(absPath)
+	}
+
+	return linkEntry
+}
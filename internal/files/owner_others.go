@@ -0,0 +1,10 @@
+//go:build !unix
+
+package files
+
+import "io/fs"
+
+// Gets the names of the user and group owning a file, files have no owners on this platform
+func fileOwner(info fs.FileInfo) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package files
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// Names of users and groups by ID, looking them up can read /etc/passwd every time
+var (
+	userNames  sync.Map
+	groupNames sync.Map
+)
+
+// Gets the names of the user and group owning a file, empty if the file system has no owners
+func fileOwner(info fs.FileInfo) (string, string) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+
+	return userName(strconv.FormatUint(uint64(stat.Uid), 10)), groupName(strconv.FormatUint(uint64(stat.Gid), 10))
+}
+
+func userName(uid string) string {
+	return lookupName(&userNames, uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+
+		return u.Username, nil
+	})
+}
+
+func groupName(gid string) string {
+	return lookupName(&groupNames, gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+
+		return g.Name, nil
+	})
+}
+
+// Gets the cached name of an ID, falling back to the ID itself if it has no name
+func lookupName(cache *sync.Map, id string, lookup func(string) (string, error)) string {
+	if name, ok := cache.Load(id); ok {
+		return name.(string)
+	}
+
+	name, err := lookup(id)
+	if err != nil {
+		name = id
+	}
+
+	cache.Store(id, name)
+	return name
+}
@@ -0,0 +1,166 @@
+package files
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+)
+
+// Entries read at once by an [EntryStream]
+const streamBatchSize = 256
+
+// Position of a listing page, known once its entries are read
+type Page struct {
+	// Page number from 1, 0 when the page starts after a cursor
+	Number int
+	// Entries per page, 0 when every entry is listed
+	PerPage int
+	// Whether more entries follow the page
+	HasNext bool
+	// Cursor of the next page, for the "cursor" query parameter
+	NextCursor string
+}
+
+// Gets the page of entries selected by the options, entries should already be filtered and sorted.
+// If the entry of the cursor no longer exists, the page is empty.
+func (o ListOptions) Paginate(entries []DirEntry) ([]DirEntry, Page) {
+	page := o.newPage()
+
+	start := 0
+	switch {
+	case o.After != "":
+		start = len(entries)
+		for i, entry := range entries {
+			if entry.Name(false) == o.After {
+				start = i + 1
+				break
+			}
+		}
+	case o.PerPage > 0:
+		// Avoid overflowing with large page numbers
+		if page.Number-1 > len(entries)/o.PerPage {
+			start = len(entries)
+		} else {
+			start = min((page.Number-1)*o.PerPage, len(entries))
+		}
+	}
+
+	entries = entries[start:]
+	if o.PerPage > 0 && len(entries) > o.PerPage {
+		entries = entries[:o.PerPage]
+		page.HasNext = true
+		page.NextCursor = encodeCursor(entries[len(entries)-1].Name(false))
+	}
+
+	return entries, page
+}
+
+func (o ListOptions) newPage() Page {
+	page := Page{Number: max(o.Page, 1), PerPage: o.PerPage}
+	if o.After != "" {
+		page.Number = 0
+	}
+
+	return page
+}
+
+func encodeCursor(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+// Reads the page of a directory selected by [ListOptions] in batches, in the order the directory is read.
+// Large directories can be listed without holding all entries in memory, entries are filtered but not sorted.
+type EntryStream struct {
+	// Position of the page, complete once Next returns no entries
+	Page Page
+
+	dir  fs.ReadDirFile
+	opts ListOptions
+	// Entries to skip before the page, and to list on it
+	skip, remaining int
+	// Whether the cursor was passed
+	started bool
+	done    bool
+	last    string
+}
+
+// Opens a directory of a file system to stream its entries
+func StreamEntriesFS(fsys fs.FS, name string, opts ListOptions) (*EntryStream, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, ok := file.(fs.ReadDirFile)
+	if !ok {
+		file.Close()
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not implemented")}
+	}
+
+	stream := &EntryStream{Page: opts.newPage(), dir: dir, opts: opts, remaining: opts.PerPage, started: opts.After == ""}
+	if stream.Page.Number > 1 && opts.PerPage > 0 {
+		// Avoid overflowing with large page numbers
+		if stream.Page.Number-1 > math.MaxInt/opts.PerPage {
+			stream.skip = math.MaxInt
+		} else {
+			stream.skip = (stream.Page.Number - 1) * opts.PerPage
+		}
+	}
+
+	return stream, nil
+}
+
+// Reads the next entries of the page, none once the page is complete
+func (s *EntryStream) Next() ([]DirEntry, error) {
+	for !s.done {
+		batch, err := s.dir.ReadDir(streamBatchSize)
+
+		var entries []DirEntry
+		for _, fsEntry := range batch {
+			entry := DirEntry{DirEntry: fsEntry}
+			if !s.opts.Matches(entry) {
+				continue
+			}
+
+			if !s.started {
+				s.started = entry.Name(false) == s.opts.After
+				continue
+			}
+
+			if s.skip > 0 {
+				s.skip--
+				continue
+			}
+
+			if s.opts.PerPage > 0 && s.remaining == 0 {
+				s.Page.HasNext = true
+				s.Page.NextCursor = encodeCursor(s.last)
+				s.done = true
+				break
+			}
+
+			entries = append(entries, entry)
+			s.last = entry.Name(false)
+			s.remaining--
+		}
+
+		if err != nil {
+			s.done = true
+			if !errors.Is(err, io.EOF) {
+				return entries, err
+			}
+		}
+
+		if len(entries) > 0 {
+			return entries, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *EntryStream) Close() error {
+	return s.dir.Close()
+}
@@ -0,0 +1,93 @@
+package files
+
+import (
+	"errors"
+	"io"
+)
+
+var errNegativeOffset = errors.New("seek to a negative offset")
+
+// Seekable reader over a stream which can only be read from the start, e.g. a compressed file in an archive.
+// Seeking backwards reopens the stream, seeking forwards skips over the content in between.
+type reopenReader struct {
+	open func() (io.ReadCloser, error)
+	size int64
+
+	stream io.ReadCloser
+	// Position of the stream
+	streamPos int64
+	// Position seeked to
+	pos int64
+}
+
+func newReopenReader(open func() (io.ReadCloser, error), size int64) *reopenReader {
+	return &reopenReader{open: open, size: size}
+}
+
+func (r *reopenReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.stream == nil || r.streamPos > r.pos {
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.streamPos < r.pos {
+		skipped, err := io.CopyN(io.Discard, r.stream, r.pos-r.streamPos)
+		r.streamPos += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.stream.Read(p)
+	r.streamPos += int64(n)
+	r.pos += int64(n)
+
+	return n, err
+}
+
+func (r *reopenReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	}
+
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+
+	r.pos = offset
+
+	return offset, nil
+}
+
+func (r *reopenReader) Close() error {
+	if r.stream == nil {
+		return nil
+	}
+
+	return r.stream.Close()
+}
+
+func (r *reopenReader) reopen() error {
+	if r.stream != nil {
+		r.stream.Close()
+	}
+
+	stream, err := r.open()
+	if err != nil {
+		r.stream = nil
+		return err
+	}
+
+	r.stream = stream
+	r.streamPos = 0
+
+	return nil
+}
@@ -2,7 +2,10 @@ package files
 
 import (
 	"errors"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 var (
@@ -29,13 +32,47 @@ func SanitisePath(rootDir, path string) (string, error) {
 		return "", ErrorSanitiseNotExists
 	}
 
-	if !hasPrefix(rootDir, absPath) {
+	if !isWithin(rootDir, absPath) {
 		return "", ErrorSanitiseUnauthorized
 	}
 
 	return absPath, nil
 }
 
+// Sanitises a URL path into a name in a file system, and checks it exists.
+// Keeping paths within the root, e.g. for symlinks, is up to the file system (see [OSFS]).
+//
+// Returns the name to open in the file system, "." for the root.
+func SanitisePathFS(fsys fs.FS, urlPath string) (string, error) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if _, err := fs.Stat(fsys, name); err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return "", ErrorSanitiseNotExists
+		case errors.Is(err, fs.ErrPermission):
+			return "", ErrorSanitiseUnauthorized
+		default:
+			return "", err
+		}
+	}
+
+	return name, nil
+}
+
+// Gets a path starts with '/' from a name in a file system
+// Should be used for display only
+func RelativeRootFS(name string) string {
+	if name == "." {
+		return "/"
+	}
+
+	return "/" + name
+}
+
 // Gets a path starts with '/' and relative to the actual rootDir
 // Should be used for display only
 func RelativeRoot(rootDir string, path string) string {
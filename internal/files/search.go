@@ -0,0 +1,155 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// Why a search stopped before walking the whole tree
+type SearchStop string
+
+const (
+	// Directories deeper than [SearchOptions.MaxDepth] were skipped
+	SearchStopDepth SearchStop = "depth"
+	// [SearchOptions.MaxResults] matches were found
+	SearchStopResults SearchStop = "results"
+	// The search ran for [SearchOptions.Timeout]
+	SearchStopTime SearchStop = "time"
+)
+
+// Limits of a filename search
+type SearchOptions struct {
+	// Levels of sub-directories searched, 1 only searches the directory itself
+	MaxDepth int
+	// Matches found before stopping
+	MaxResults int
+	// Time spent walking before stopping
+	Timeout time.Duration
+}
+
+// Entry found by a [Search]
+type SearchResult struct {
+	// Path of the entry from the searched directory, e.g. "docs/readme.md"
+	Path  string
+	Entry DirEntry
+}
+
+// Recursive search of file and directory names in a file system.
+// Hidden paths are skipped if fsys is a [FilterFS], symlinked directories are listed but not searched.
+type Search struct {
+	// The search query, a glob if it contains any of "*?[", otherwise a substring. Case-insensitive
+	Query string
+	// Matches found so far
+	Count int
+	// Why the search stopped early, empty if the whole tree was searched
+	Stopped SearchStop
+
+	fsys    fs.FS
+	dir     string
+	opts    SearchOptions
+	pattern string
+	isGlob  bool
+}
+
+// Prepares a search of dir in fsys. Returns [ErrInvalidSearch] if the query is empty or an invalid glob
+func NewSearch(fsys fs.FS, dir, query string, opts SearchOptions) (*Search, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidSearch)
+	}
+
+	s := &Search{Query: query, fsys: fsys, dir: dir, opts: opts, pattern: strings.ToLower(query)}
+
+	s.isGlob = strings.ContainsAny(query, "*?[")
+	if s.isGlob {
+		if _, err := path.Match(s.pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: invalid pattern '%s'", ErrInvalidSearch, query)
+		}
+	}
+
+	return s, nil
+}
+
+// Checks whether a name matches the query
+func (s *Search) Matches(name string) bool {
+	name = strings.ToLower(name)
+	if s.isGlob {
+		matched, _ := path.Match(s.pattern, name)
+		return matched
+	}
+
+	return strings.Contains(name, s.pattern)
+}
+
+// Walks the directory depth-first, yielding matches as they are found.
+// The walk stops when ctx is done, or a limit of the options is reached.
+func (s *Search) Results(ctx context.Context) iter.Seq[SearchResult] {
+	return func(yield func(SearchResult) bool) {
+		if s.opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+			defer cancel()
+		}
+
+		s.walk(ctx, "", 1, yield)
+	}
+}
+
+// Searches the directory at rel from the searched directory, returns false once the search stops
+func (s *Search) walk(ctx context.Context, rel string, depth int, yield func(SearchResult) bool) bool {
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.Stopped = SearchStopTime
+		}
+		return false
+	}
+
+	// Unreadable directories are skipped, like hidden ones
+	fsEntries, err := fs.ReadDir(s.fsys, path.Join(s.dir, rel))
+	if err != nil {
+		return true
+	}
+
+	for _, fsEntry := range fsEntries {
+		entry := DirEntry{DirEntry: fsEntry}
+		entryRel := path.Join(rel, fsEntry.Name())
+
+		if s.Matches(fsEntry.Name()) {
+			s.Count++
+			if !yield(SearchResult{Path: entryRel, Entry: entry}) {
+				return false
+			}
+
+			if s.opts.MaxResults > 0 && s.Count >= s.opts.MaxResults {
+				s.Stopped = SearchStopResults
+				return false
+			}
+		}
+
+		// Symlinks may lead back up the tree
+		if !entry.IsDir() || entry.LinkTarget() != "" || !entry.Followable() {
+			continue
+		}
+
+		if s.opts.MaxDepth > 0 && depth >= s.opts.MaxDepth {
+			if s.Stopped == "" {
+				s.Stopped = SearchStopDepth
+			}
+			continue
+		}
+
+		if !s.walk(ctx, entryRel, depth+1, yield) {
+			return false
+		}
+	}
+
+	return true
+}
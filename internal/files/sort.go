@@ -0,0 +1,286 @@
+package files
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidListOptions = errors.New("invalid listing options")
+
+// Key to sort directory entries by
+type SortKey string
+
+const (
+	SortName    SortKey = "name"
+	SortSize    SortKey = "size"
+	SortModTime SortKey = "mtime"
+	// By extension, then by name
+	SortType SortKey = "type"
+	// In the order the directory is read, see [EntryStream]
+	SortNone SortKey = "none"
+)
+
+// Most entries a listing page can have
+const MaxPerPage = 10000
+
+// Sorting and filtering of a directory listing
+type ListOptions struct {
+	Sort SortKey
+	Desc bool
+	// Glob matched against entry names, case-insensitive. Empty lists everything
+	Filter string
+
+	// Page number from 1, 0 for the first page
+	Page int
+	// Entries per page, 0 lists every entry
+	PerPage int
+	// Name of the entry the page starts after, decoded from a cursor (see [Page.NextCursor])
+	After string
+}
+
+// Reads the "sort", "order", "filter", "page", "per_page" and "cursor" query parameters,
+// e.g. ?sort=mtime&order=desc&filter=*.log&page=2&per_page=100.
+// Missing parameters sort by name in ascending order, and list every entry.
+func ParseListOptions(query url.Values) (ListOptions, error) {
+	opts := ListOptions{Sort: SortName}
+
+	switch key := SortKey(query.Get("sort")); key {
+	case "":
+	case SortName, SortSize, SortModTime, SortType:
+		opts.Sort = key
+	case SortNone:
+		opts.Sort = key
+	default:
+		return opts, fmt.Errorf("%w: unknown sort key '%s'", ErrInvalidListOptions, key)
+	}
+
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("%w: unknown order '%s'", ErrInvalidListOptions, order)
+	}
+
+	opts.Filter = query.Get("filter")
+	if _, err := path.Match(opts.Filter, ""); err != nil {
+		return opts, fmt.Errorf("%w: invalid filter '%s'", ErrInvalidListOptions, opts.Filter)
+	}
+
+	var err error
+	if opts.Page, err = parsePositive(query, "page", math.MaxInt); err != nil {
+		return opts, err
+	}
+	if opts.PerPage, err = parsePositive(query, "per_page", MaxPerPage); err != nil {
+		return opts, err
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		if opts.Page != 0 {
+			return opts, fmt.Errorf("%w: 'page' and 'cursor' cannot be used together", ErrInvalidListOptions)
+		}
+
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(after) == 0 {
+			return opts, fmt.Errorf("%w: invalid cursor '%s'", ErrInvalidListOptions, cursor)
+		}
+		opts.After = string(after)
+	}
+
+	return opts, nil
+}
+
+// Reads a query parameter between 1 and maxValue, 0 if it is missing
+func parsePositive(query url.Values, name string, maxValue int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxValue {
+		return 0, fmt.Errorf("%w: '%s' must be a number between 1 and %d", ErrInvalidListOptions, name, maxValue)
+	}
+
+	return n, nil
+}
+
+// Checks whether the name of an entry matches the filter
+func (o ListOptions) Matches(entry DirEntry) bool {
+	if o.Filter == "" {
+		return true
+	}
+
+	matched, _ := path.Match(strings.ToLower(o.Filter), strings.ToLower(entry.Name(false)))
+	return matched
+}
+
+// Filters and sorts entries, directories are always listed first.
+// With [SortNone], entries are only filtered.
+func (o ListOptions) Apply(entries []DirEntry) []DirEntry {
+	if o.Filter != "" {
+		entries = slices.DeleteFunc(entries, func(entry DirEntry) bool {
+			return !o.Matches(entry)
+		})
+	}
+
+	if o.Sort == SortNone {
+		return entries
+	}
+
+	// Infos can take a stat call each, so they are looked up once per entry rather than per comparison
+	sorted := make([]sortedEntry, len(entries))
+	for i, entry := range entries {
+		sorted[i] = o.sortedEntry(entry)
+	}
+
+	compare := o.compareFunc()
+
+	slices.SortStableFunc(sorted, func(a, b sortedEntry) int {
+		if a.isDir != b.isDir {
+			if a.isDir {
+				return -1
+			}
+			return 1
+		}
+
+		result := compare(a, b)
+		if result == 0 {
+			result = CompareNatural(a.name, b.name)
+		}
+
+		if o.Desc {
+			return -result
+		}
+		return result
+	})
+
+	for i, item := range sorted {
+		entries[i] = item.entry
+	}
+
+	return entries
+}
+
+// An entry with the values it is sorted by
+type sortedEntry struct {
+	entry   DirEntry
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+// Looks up the values an entry is sorted by
+func (o ListOptions) sortedEntry(entry DirEntry) sortedEntry {
+	sorted := sortedEntry{entry: entry, name: entry.Name(false), isDir: entry.IsDir()}
+
+	if o.Sort != SortSize && o.Sort != SortModTime {
+		return sorted
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return sorted
+	}
+
+	if !info.IsDir() {
+		sorted.size = info.Size()
+	}
+	sorted.modTime = info.ModTime()
+
+	return sorted
+}
+
+func (o ListOptions) compareFunc() func(a, b sortedEntry) int {
+	switch o.Sort {
+	case SortSize:
+		return func(a, b sortedEntry) int {
+			return cmp.Compare(a.size, b.size)
+		}
+	case SortModTime:
+		return func(a, b sortedEntry) int {
+			return a.modTime.Compare(b.modTime)
+		}
+	case SortType:
+		return func(a, b sortedEntry) int {
+			return strings.Compare(strings.ToLower(path.Ext(a.name)), strings.ToLower(path.Ext(b.name)))
+		}
+	default:
+		return func(a, b sortedEntry) int {
+			return CompareNatural(a.name, b.name)
+		}
+	}
+}
+
+// Sorts by directories first, then by name
+func Sort(entries []DirEntry) {
+	ListOptions{Sort: SortName}.Apply(entries)
+}
+
+// Compares names the way people read them: case-insensitive, with numbers compared by value,
+// e.g. "file2" before "file10" and "v1.9.0" before "v1.10.0".
+func CompareNatural(a, b string) int {
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			numStartA, numStartB := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+
+			numA := strings.TrimLeft(a[numStartA:i], "0")
+			numB := strings.TrimLeft(b[numStartB:j], "0")
+
+			// More digits without leading zeros is a larger number
+			if result := cmp.Compare(len(numA), len(numB)); result != 0 {
+				return result
+			}
+			if result := strings.Compare(numA, numB); result != 0 {
+				return result
+			}
+
+			continue
+		}
+
+		charA, charB := toLower(a[i]), toLower(b[j])
+		if charA != charB {
+			return cmp.Compare(charA, charB)
+		}
+
+		i++
+		j++
+	}
+
+	if result := cmp.Compare(len(a)-i, len(b)-j); result != 0 {
+		return result
+	}
+
+	// Equal apart from case or leading zeros, keep the order stable
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+
+	return c
+}
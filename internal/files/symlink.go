@@ -0,0 +1,105 @@
+package files
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// How an [OSFS] treats symlinks
+type SymlinkPolicy string
+
+const (
+	// Symlinks are not followed, and left out of listings
+	SymlinksNever SymlinkPolicy = "never"
+	// Symlinks are followed if their target is within the root
+	SymlinksWithinRoot SymlinkPolicy = "root"
+	// Symlinks are followed if their target is within the root or one of the allowed directories
+	SymlinksAllowList SymlinkPolicy = "allow"
+	// Symlinks are listed with their target, but not followed
+	SymlinksShow SymlinkPolicy = "show"
+)
+
+// Checks whether the input is a known symlink policy
+func (p SymlinkPolicy) Valid() bool {
+	switch p {
+	case SymlinksNever, SymlinksWithinRoot, SymlinksAllowList, SymlinksShow:
+		return true
+	default:
+		return false
+	}
+}
+
+// Checks whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	if len(path) < len(dir) || !hasPrefix(dir, path) {
+		return false
+	}
+
+	return len(path) == len(dir) || path[len(dir)] == filepath.Separator || strings.HasSuffix(dir, string(filepath.Separator))
+}
+
+// Directory of an [OSFS], listing symlinks according to its policy
+type osDir struct {
+	*os.File
+	fsys OSFS
+	name string
+}
+
+func (d *osDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	for {
+		entries, err := d.File.ReadDir(count)
+
+		result := make([]fs.DirEntry, 0, len(entries))
+		for _, entry := range entries {
+			if entry.Type()&fs.ModeSymlink == 0 {
+				result = append(result, entry)
+				continue
+			}
+
+			if d.fsys.symlinks == SymlinksNever {
+				continue
+			}
+
+			result = append(result, d.fsys.symlinkEntry(d.name, entry))
+		}
+
+		// Keep reading when a whole batch was left out, as an empty batch means the end of the directory
+		if count > 0 && len(result) == 0 && err == nil {
+			continue
+		}
+
+		return result, err
+	}
+}
+
+// Directory entry of a symlink, with its target
+type symlinkDirEntry struct {
+	fs.DirEntry
+	target string
+	// Info of the target if the link is followed
+	targetInfo fs.FileInfo
+}
+
+func (e symlinkDirEntry) LinkTarget() string {
+	return e.target
+}
+
+func (e symlinkDirEntry) Followable() bool {
+	return e.targetInfo != nil
+}
+
+// Followed links to directories are listed as directories
+func (e symlinkDirEntry) IsDir() bool {
+	return e.targetInfo != nil && e.targetInfo.IsDir()
+}
+
+// Gets the info of the target if the link is followed, otherwise of the link itself
+func (e symlinkDirEntry) Info() (fs.FileInfo, error) {
+	if e.targetInfo != nil {
+		return e.targetInfo, nil
+	}
+
+	return e.DirEntry.Info()
+}
@@ -0,0 +1,159 @@
+package files
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// A root of a [UnionFS]
+type Layer struct {
+	// Shown in directory listings next to the entries from this layer
+	Name string
+	FS   fs.FS
+}
+
+// Overlays file systems, a path is served from the first layer containing it.
+// Directory listings merge the entries of all layers, marking which layer each entry comes from.
+//
+// Each layer keeps its own rules, e.g. symlinks in an [OSFS] layer must stay within that layer's root.
+type UnionFS struct {
+	layers []Layer
+}
+
+func NewUnionFS(layers ...Layer) *UnionFS {
+	return &UnionFS{layers: layers}
+}
+
+func (u *UnionFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	for i, layer := range u.layers {
+		f, err := layer.FS.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			// The path is in this layer but cannot be opened, e.g. a symlink leading out of it
+			return nil, err
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			return f, nil
+		}
+
+		return &unionDir{File: f, union: u, name: name, firstLayer: i}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Gets the path name leads to after following symlinks, in the first layer containing it.
+// Returns false if the path does not exist or leads out of that layer's root.
+func (u *UnionFS) ResolvePath(name string) (string, bool) {
+	for _, layer := range u.layers {
+		if _, err := fs.Stat(layer.FS, name); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if resolver, ok := layer.FS.(pathResolver); ok {
+			return resolver.ResolvePath(name)
+		}
+
+		return name, true
+	}
+
+	return "", false
+}
+
+// Directory entry from a layer of a [UnionFS]
+type layerDirEntry struct {
+	fs.DirEntry
+	layer string
+}
+
+func (e layerDirEntry) Layer() string {
+	return e.layer
+}
+
+func (e layerDirEntry) LinkTarget() string {
+	return DirEntry{DirEntry: e.DirEntry}.LinkTarget()
+}
+
+func (e layerDirEntry) Followable() bool {
+	return DirEntry{DirEntry: e.DirEntry}.Followable()
+}
+
+type unionDir struct {
+	fs.File
+	union      *UnionFS
+	name       string
+	firstLayer int
+
+	entries []fs.DirEntry
+	read    bool
+	offset  int
+}
+
+func (d *unionDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !d.read {
+		if err := d.merge(); err != nil {
+			return nil, err
+		}
+		d.read = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if count > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > 0 && count < len(remaining) {
+		remaining = remaining[:count]
+	}
+	d.offset += len(remaining)
+
+	return remaining, nil
+}
+
+// Reads the directory in all layers, entries from earlier layers hide the ones with the same name below
+func (d *unionDir) merge() error {
+	seen := make(map[string]bool)
+
+	for i, layer := range d.union.layers[d.firstLayer:] {
+		entries, err := fs.ReadDir(layer.FS, d.name)
+		if err != nil {
+			// Lower layers may not have the directory, or have a file with the same name
+			if i == 0 {
+				return err
+			}
+
+			continue
+		}
+
+		for _, entry := range entries {
+			if seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+
+			d.entries = append(d.entries, layerDirEntry{DirEntry: entry, layer: layer.Name})
+		}
+	}
+
+	slices.SortFunc(d.entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return nil
+}
@@ -0,0 +1,402 @@
+// Full-text search of the text files of a file system, with an index held in memory
+package fulltext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Defaults of [Options] left at zero
+const (
+	DefaultMaxFileSize = 1 << 20
+	DefaultInterval    = 30 * time.Second
+)
+
+// Bytes looked at to tell binary files apart
+const sniffLength = 8000
+
+// Time to wait for more events before indexing changed paths, as writing a file sends several
+const watchDelay = 100 * time.Millisecond
+
+type Options struct {
+	// Files larger than this are not indexed
+	MaxFileSize int64
+	// Time between scans for changed files, when Dirs cannot be watched
+	Interval time.Duration
+	// Directories on disk the file system is made of, each one mapped to its root.
+	// They are watched for changes, the whole file system is only scanned again if events are lost.
+	Dirs []string
+}
+
+// Index of the text files of a file system, kept up to date by [Index.Run]
+type Index struct {
+	fsys fs.FS
+	opts Options
+
+	mu   sync.RWMutex
+	docs map[string]*document
+	// Documents containing each trigram of their lowercased content
+	trigrams map[uint32]map[*document]struct{}
+
+	ready atomic.Bool
+
+	// Watches directories of Dirs while [Index.Run] runs
+	watcher     *fsnotify.Watcher
+	watchFailed bool
+}
+
+// Indexed text file
+type document struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	content  []byte
+	trigrams []uint32
+}
+
+func New(fsys fs.FS, opts Options) *Index {
+	if opts.MaxFileSize == 0 {
+		opts.MaxFileSize = DefaultMaxFileSize
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+
+	return &Index{
+		fsys:     fsys,
+		opts:     opts,
+		docs:     make(map[string]*document),
+		trigrams: make(map[uint32]map[*document]struct{}),
+	}
+}
+
+// Builds the index, then keeps it up to date until ctx is done.
+// Changes are followed with file system events when [Options.Dirs] can be watched,
+// otherwise the file system is scanned for changed files at every interval.
+func (i *Index) Run(ctx context.Context) {
+	if len(i.opts.Dirs) > 0 {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logger.Printf(logger.LogWarn, "Cannot watch files to index, scanning them every %s: %v\n", i.opts.Interval, err)
+		} else {
+			i.watcher = watcher
+			defer watcher.Close()
+		}
+	}
+
+	for {
+		start := time.Now()
+		if err := i.Refresh(ctx); err != nil {
+			return
+		}
+
+		if !i.ready.Swap(true) {
+			logger.Printf(logger.LogNormal, "Indexed %d text files in %s\n", i.Files(), time.Since(start).Round(time.Millisecond))
+		}
+
+		if i.watcher != nil && !i.watchFailed {
+			if !i.followEvents(ctx) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(i.opts.Interval):
+		}
+	}
+}
+
+// Indexes changed paths as their events come in.
+// Returns false once ctx is done, or true if events were lost or a directory cannot be watched, for a scan to catch up.
+func (i *Index) followEvents(ctx context.Context) bool {
+	changed := make(map[string]bool)
+	var delay <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-i.watcher.Events:
+			if !ok {
+				return false
+			}
+
+			if name, ok := i.fsName(event.Name); ok {
+				changed[name] = true
+				if delay == nil {
+					delay = time.After(watchDelay)
+				}
+			}
+		case err, ok := <-i.watcher.Errors:
+			if !ok {
+				return false
+			}
+
+			logger.Printf(logger.LogWarn, "Lost events of indexed files, scanning them again: %v\n", err)
+			return true
+		case <-delay:
+			delay = nil
+
+			for name := range changed {
+				if err := i.refreshPath(ctx, name); err != nil {
+					return false
+				}
+			}
+			clear(changed)
+
+			if i.watchFailed {
+				return true
+			}
+		}
+	}
+}
+
+// Gets the path in the file system of a path on disk under one of [Options.Dirs]
+func (i *Index) fsName(osPath string) (string, bool) {
+	for _, dir := range i.opts.Dirs {
+		rel, err := filepath.Rel(dir, osPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		return filepath.ToSlash(rel), true
+	}
+
+	return "", false
+}
+
+// Watches a directory of the file system in each of [Options.Dirs] it is in
+func (i *Index) watchDir(dir string) {
+	if i.watcher == nil || i.watchFailed {
+		return
+	}
+
+	for _, root := range i.opts.Dirs {
+		err := i.watcher.Add(filepath.Join(root, filepath.FromSlash(dir)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			// e.g. the limit of watches is reached
+			logger.Printf(logger.LogWarn, "Cannot watch files to index, scanning them every %s: %v\n", i.opts.Interval, err)
+			i.watchFailed = true
+			return
+		}
+	}
+}
+
+// Indexes a changed path again: a file, a directory with everything under it, or a removed path
+func (i *Index) refreshPath(ctx context.Context, name string) error {
+	if name == "." {
+		return i.Refresh(ctx)
+	}
+
+	seen := make(map[string]bool)
+
+	// Read from its directory, to treat it like the entries of a scan
+	dir := path.Dir(name)
+	entries, _ := fs.ReadDir(i.fsys, dir)
+	for _, fsEntry := range entries {
+		if fsEntry.Name() == path.Base(name) {
+			if err := i.scanEntry(ctx, dir, fsEntry, seen); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for docPath, doc := range i.docs {
+		if (docPath == name || strings.HasPrefix(docPath, name+"/")) && !seen[docPath] {
+			i.remove(doc)
+		}
+	}
+
+	return nil
+}
+
+// Scans the file system once, reading files which were added or changed and dropping removed ones.
+// Returns an error only if ctx is done.
+func (i *Index) Refresh(ctx context.Context) error {
+	seen := make(map[string]bool)
+
+	if err := i.scanDir(ctx, ".", seen); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for name, doc := range i.docs {
+		if !seen[name] {
+			i.remove(doc)
+		}
+	}
+
+	return nil
+}
+
+func (i *Index) scanDir(ctx context.Context, dir string, seen map[string]bool) error {
+	// Watched before reading it, so files added meanwhile are not missed
+	i.watchDir(dir)
+
+	// Unreadable directories are left out, like hidden ones
+	entries, err := fs.ReadDir(i.fsys, dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, fsEntry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := i.scanEntry(ctx, dir, fsEntry, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Indexes an entry of dir if it was added or changed, and everything under it for a directory
+func (i *Index) scanEntry(ctx context.Context, dir string, fsEntry fs.DirEntry, seen map[string]bool) error {
+	entry := files.DirEntry{DirEntry: fsEntry}
+	name := path.Join(dir, fsEntry.Name())
+
+	// Symlinks may lead back up the tree, or to files indexed through their own path
+	if entry.LinkTarget() != "" {
+		return nil
+	}
+
+	if entry.IsDir() {
+		return i.scanDir(ctx, name, seen)
+	}
+
+	info, err := entry.Info()
+	if err != nil || !info.Mode().IsRegular() || info.Size() > i.opts.MaxFileSize {
+		return nil
+	}
+
+	seen[name] = true
+
+	i.mu.RLock()
+	doc, ok := i.docs[name]
+	i.mu.RUnlock()
+
+	if ok && doc.size == info.Size() && doc.modTime.Equal(info.ModTime()) {
+		return nil
+	}
+
+	i.indexFile(name, info)
+	return nil
+}
+
+// Reads and indexes a file, or drops it if it is no longer text
+func (i *Index) indexFile(name string, info fs.FileInfo) {
+	content, err := fs.ReadFile(i.fsys, name)
+
+	var doc *document
+	if err == nil && isText(content) {
+		doc = &document{path: name, modTime: info.ModTime(), size: info.Size(), content: content, trigrams: trigramsOf(bytes.ToLower(content))}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if old, ok := i.docs[name]; ok {
+		i.remove(old)
+	}
+
+	if doc == nil {
+		return
+	}
+
+	i.docs[name] = doc
+	for _, trigram := range doc.trigrams {
+		posting, ok := i.trigrams[trigram]
+		if !ok {
+			posting = make(map[*document]struct{})
+			i.trigrams[trigram] = posting
+		}
+		posting[doc] = struct{}{}
+	}
+}
+
+// Drops a document, the lock must be held
+func (i *Index) remove(doc *document) {
+	delete(i.docs, doc.path)
+
+	for _, trigram := range doc.trigrams {
+		posting := i.trigrams[trigram]
+		delete(posting, doc)
+		if len(posting) == 0 {
+			delete(i.trigrams, trigram)
+		}
+	}
+}
+
+// Checks whether the first scan is done, searches before it only see part of the files
+func (i *Index) Ready() bool {
+	return i.ready.Load()
+}
+
+// Gets the number of indexed files
+func (i *Index) Files() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.docs)
+}
+
+// Text files are valid UTF-8 without NUL bytes, looking at their start
+func isText(content []byte) bool {
+	sniff := content[:min(len(content), sniffLength)]
+	if bytes.IndexByte(sniff, 0) != -1 {
+		return false
+	}
+
+	// The sniffed part may end in the middle of a character
+	for range utf8.UTFMax - 1 {
+		if utf8.Valid(sniff) || len(sniff) == 0 {
+			break
+		}
+		sniff = sniff[:len(sniff)-1]
+	}
+
+	return utf8.Valid(sniff)
+}
+
+// Gets the distinct trigrams of text
+func trigramsOf(text []byte) []uint32 {
+	set := make(map[uint32]struct{})
+	for j := 0; j+3 <= len(text); j++ {
+		set[trigram(text[j:j+3])] = struct{}{}
+	}
+
+	trigrams := make([]uint32, 0, len(set))
+	for t := range set {
+		trigrams = append(trigrams, t)
+	}
+
+	return trigrams
+}
+
+func trigram(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
@@ -0,0 +1,168 @@
+package fulltext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Shortest query, shorter ones would match most files without narrowing them down by trigrams
+const MinQueryLength = 3
+
+// Longest line shown, longer ones are cut
+const maxLineLength = 500
+
+type SearchOptions struct {
+	// Matching lines found before stopping, 0 for no limit
+	MaxMatches int
+	// Lines shown before and after each matching line
+	Context int
+}
+
+// Files with matching lines
+type Results struct {
+	Files []FileMatches
+	// Matching lines found
+	Count int
+	// Whether the search stopped at [SearchOptions.MaxMatches]
+	Truncated bool
+}
+
+// Matching lines of a file
+type FileMatches struct {
+	// Path of the file in the file system, e.g. "docs/guide.md"
+	Path    string
+	Matches []LineMatch
+}
+
+// A matching line with its context
+type LineMatch struct {
+	// Line number from 1
+	Line   int
+	Text   string
+	Before []string
+	After  []string
+}
+
+// Searches the indexed files under dir for lines containing the query, ignoring case.
+// Files are checked to still exist in fsys, so paths hidden since the last scan are left out.
+func (i *Index) Search(fsys fs.FS, dir, query string, opts SearchOptions) (Results, error) {
+	if len(strings.TrimSpace(query)) < MinQueryLength {
+		return Results{}, fmt.Errorf("%w: search text must be at least %d characters", ErrInvalidQuery, MinQueryLength)
+	}
+
+	needle := bytes.ToLower([]byte(query))
+	docs := i.candidates(needle, dir)
+
+	var results Results
+	for _, doc := range docs {
+		if _, err := fs.Stat(fsys, doc.path); err != nil {
+			continue
+		}
+
+		fileMatches := FileMatches{Path: doc.path}
+		// The newline ending the last line does not start another one
+		lines := bytes.Split(bytes.TrimSuffix(doc.content, []byte("\n")), []byte("\n"))
+
+		for n, line := range lines {
+			if !bytes.Contains(bytes.ToLower(line), needle) {
+				continue
+			}
+
+			if opts.MaxMatches > 0 && results.Count >= opts.MaxMatches {
+				results.Truncated = true
+				break
+			}
+
+			fileMatches.Matches = append(fileMatches.Matches, LineMatch{
+				Line:   n + 1,
+				Text:   lineText(line),
+				Before: linesText(lines[max(n-opts.Context, 0):n]),
+				After:  linesText(lines[n+1 : min(n+1+opts.Context, len(lines))]),
+			})
+			results.Count++
+		}
+
+		if len(fileMatches.Matches) > 0 {
+			results.Files = append(results.Files, fileMatches)
+		}
+
+		if results.Truncated {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// Gets the documents under dir containing every trigram of the needle, sorted by path
+func (i *Index) candidates(needle []byte, dir string) []*document {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	// Start from the rarest trigram
+	var postings []map[*document]struct{}
+	for _, t := range trigramsOf(needle) {
+		posting, ok := i.trigrams[t]
+		if !ok {
+			return nil
+		}
+		postings = append(postings, posting)
+	}
+	slices.SortFunc(postings, func(a, b map[*document]struct{}) int {
+		return len(a) - len(b)
+	})
+
+	prefix := ""
+	if dir != "." {
+		prefix = dir + "/"
+	}
+
+	var docs []*document
+	for doc := range postings[0] {
+		if !strings.HasPrefix(doc.path, prefix) {
+			continue
+		}
+
+		inAll := true
+		for _, posting := range postings[1:] {
+			if _, ok := posting[doc]; !ok {
+				inAll = false
+				break
+			}
+		}
+
+		if inAll {
+			docs = append(docs, doc)
+		}
+	}
+
+	slices.SortFunc(docs, func(a, b *document) int {
+		return strings.Compare(a.path, b.path)
+	})
+
+	return docs
+}
+
+func lineText(line []byte) string {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) > maxLineLength {
+		return strings.ToValidUTF8(string(line[:maxLineLength]), "") + "…"
+	}
+
+	return string(line)
+}
+
+func linesText(lines [][]byte) []string {
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		texts = append(texts, lineText(line))
+	}
+
+	return texts
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package listener
+
+import (
+	"errors"
+	"syscall"
+)
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
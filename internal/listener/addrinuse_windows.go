@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package listener
+
+import (
+	"errors"
+	"syscall"
+)
+
+// WSAEADDRINUSE, Windows does not report the address in use error as EADDRINUSE
+const wsaeAddrInUse = syscall.Errno(10048)
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, wsaeAddrInUse)
+}
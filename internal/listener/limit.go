@@ -0,0 +1,66 @@
+package listener
+
+import (
+	"net"
+	"sync"
+)
+
+// Caps the number of connections open at once, shared by all listeners it wraps
+type Limiter struct {
+	slots chan struct{}
+}
+
+// Creates a limiter allowing up to max connections at once
+func NewLimiter(max int) *Limiter {
+	return &Limiter{
+		slots: make(chan struct{}, max),
+	}
+}
+
+// Wraps a listener so Accept waits for a free slot before accepting a connection
+func (l *Limiter) Wrap(ln net.Listener) net.Listener {
+	return &limitListener{Listener: ln, limiter: l, done: make(chan struct{})}
+}
+
+type limitListener struct {
+	net.Listener
+	limiter   *Limiter
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (ln *limitListener) Accept() (net.Conn, error) {
+	select {
+	case ln.limiter.slots <- struct{}{}:
+	case <-ln.done:
+		return nil, net.ErrClosed
+	}
+
+	conn, err := ln.Listener.Accept()
+	if err != nil {
+		<-ln.limiter.slots
+		return nil, err
+	}
+
+	return &limitConn{Conn: conn, limiter: ln.limiter}, nil
+}
+
+func (ln *limitListener) Close() error {
+	err := ln.Listener.Close()
+	ln.closeOnce.Do(func() { close(ln.done) })
+
+	return err
+}
+
+type limitConn struct {
+	net.Conn
+	limiter     *Limiter
+	releaseOnce sync.Once
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(func() { <-c.limiter.slots })
+
+	return err
+}
@@ -0,0 +1,156 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	NetworkTCP     = "tcp"
+	NetworkUnix    = "unix"
+	NetworkSystemd = "systemd"
+
+	unixPrefix    = "unix:"
+	systemdPrefix = "systemd"
+
+	// Number of following ports tried when a port is in use, before letting the system pick one
+	portFallbackAttempts = 100
+)
+
+var ErrSocketInUse = errors.New("socket is in use by another process")
+
+// An address to listen on
+type Address struct {
+	// One of [NetworkTCP], [NetworkUnix] or [NetworkSystemd]
+	Network string
+	// host:port for TCP, socket path for Unix, optional socket name for systemd
+	Addr string
+	// For TCP, try the following ports then any free port when the port is in use
+	PortFallback bool
+}
+
+type Options struct {
+	// File mode to set on Unix sockets, 0 keeps the mode from umask
+	SocketMode fs.FileMode
+}
+
+// Parses "unix:/path/to.sock", "systemd" or "systemd:name" addresses.
+// Returns false if the value is neither, and should be treated as a TCP host:port.
+func Parse(value string) (Address, bool) {
+	if path, found := strings.CutPrefix(value, unixPrefix); found {
+		return Address{Network: NetworkUnix, Addr: path}, true
+	}
+
+	if value == systemdPrefix {
+		return Address{Network: NetworkSystemd}, true
+	}
+
+	if name, found := strings.CutPrefix(value, systemdPrefix+":"); found {
+		return Address{Network: NetworkSystemd, Addr: name}, true
+	}
+
+	return Address{}, false
+}
+
+func (a Address) String() string {
+	switch a.Network {
+	case NetworkUnix:
+		return unixPrefix + a.Addr
+	case NetworkSystemd:
+		if a.Addr == "" {
+			return systemdPrefix
+		}
+
+		return systemdPrefix + ":" + a.Addr
+	default:
+		return a.Addr
+	}
+}
+
+// Creates listeners for the address.
+// Systemd addresses can return multiple listeners, one for each inherited socket.
+func Listen(addr Address, opts Options) ([]net.Listener, error) {
+	switch addr.Network {
+	case NetworkTCP:
+		listener, err := listenTCP(addr.Addr, addr.PortFallback)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{listener}, nil
+	case NetworkUnix:
+		listener, err := listenUnix(addr.Addr, opts.SocketMode)
+		if err != nil {
+			return nil, err
+		}
+
+		return []net.Listener{listener}, nil
+	case NetworkSystemd:
+		return systemdListeners(addr.Addr)
+	default:
+		return nil, fmt.Errorf("unknown network '%s'", addr.Network)
+	}
+}
+
+// Listens on a TCP address, moving on to the next port when in use if fallback is enabled
+func listenTCP(addr string, fallback bool) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err == nil || !fallback || !isAddrInUse(err) {
+		return listener, err
+	}
+
+	host, portValue, splitErr := net.SplitHostPort(addr)
+	port, convErr := strconv.Atoi(portValue)
+	if splitErr != nil || convErr != nil {
+		return nil, err
+	}
+
+	for next := port + 1; next <= port+portFallbackAttempts && next <= 65535; next++ {
+		listener, err = net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(next)))
+		if err == nil || !isAddrInUse(err) {
+			return listener, err
+		}
+	}
+
+	// Let the system pick a free port
+	return net.Listen("tcp", net.JoinHostPort(host, "0"))
+}
+
+// Listens on a Unix socket, removing a stale socket file left by a previous process
+func listenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("'%s' exists and is not a socket", path)
+		}
+
+		// A socket nobody is accepting on is stale
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrSocketInUse, path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("cannot remove stale socket '%s': %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("cannot set mode of socket '%s': %w", path, err)
+		}
+	}
+
+	return listener, nil
+}
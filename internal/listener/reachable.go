@@ -0,0 +1,44 @@
+package listener
+
+import (
+	"net"
+)
+
+// Lists the addresses a listener can be reached at.
+// A TCP listener on an unspecified IP (0.0.0.0 or ::) is reachable on the IP of every interface,
+// other listeners only on their own address.
+func ReachableAddrs(addr net.Addr) []net.Addr {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok || !tcpAddr.IP.IsUnspecified() {
+		return []net.Addr{addr}
+	}
+
+	interfaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return []net.Addr{addr}
+	}
+
+	// IPv6 wildcard listeners accept IPv4 too, IPv4 ones only IPv4
+	ipv4Only := tcpAddr.IP.To4() != nil
+
+	reachable := make([]net.Addr, 0, len(interfaceAddrs))
+	for _, interfaceAddr := range interfaceAddrs {
+		ipNet, ok := interfaceAddr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		// Link-local addresses need a zone to be usable, which browsers do not support
+		if ipNet.IP.IsLinkLocalUnicast() || (ipv4Only && ipNet.IP.To4() == nil) {
+			continue
+		}
+
+		reachable = append(reachable, &net.TCPAddr{IP: ipNet.IP, Port: tcpAddr.Port})
+	}
+
+	if len(reachable) == 0 {
+		return []net.Addr{addr}
+	}
+
+	return reachable
+}
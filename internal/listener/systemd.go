@@ -0,0 +1,99 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// First file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+var ErrNoSystemdSockets = errors.New("no sockets passed by systemd (LISTEN_FDS)")
+
+type systemdSocket struct {
+	name     string
+	listener net.Listener
+}
+
+var (
+	systemdOnce    sync.Once
+	systemdSockets []systemdSocket
+	systemdErr     error
+)
+
+// Gets listeners inherited through systemd socket activation.
+// If name is not empty, only sockets with a matching FileDescriptorName are returned.
+func systemdListeners(name string) ([]net.Listener, error) {
+	systemdOnce.Do(func() {
+		systemdSockets, systemdErr = inheritSystemdSockets()
+	})
+
+	if systemdErr != nil {
+		return nil, systemdErr
+	}
+
+	listeners := make([]net.Listener, 0, len(systemdSockets))
+	for _, socket := range systemdSockets {
+		if name == "" || socket.name == name {
+			listeners = append(listeners, socket.listener)
+		}
+	}
+
+	if len(listeners) == 0 {
+		if name != "" {
+			return nil, fmt.Errorf("%w with name '%s'", ErrNoSystemdSockets, name)
+		}
+
+		return nil, ErrNoSystemdSockets
+	}
+
+	return listeners, nil
+}
+
+// Reads LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES as described in sd_listen_fds(3).
+// The variables are unset afterwards so child processes do not inherit them.
+func inheritSystemdSockets() ([]systemdSocket, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, ErrNoSystemdSockets
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, ErrNoSystemdSockets
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	sockets := make([]systemdSocket, 0, count)
+	for i := range count {
+		fd := listenFdsStart + i
+
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(fd), "systemd-socket-"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherited file descriptor %d is not a listening socket: %w", fd, err)
+		}
+
+		sockets = append(sockets, systemdSocket{name, listener})
+	}
+
+	return sockets, nil
+}
@@ -26,6 +26,9 @@ var (
 
 var LogWithColor = true
 
+// Whether to log extra details, e.g. negotiated TLS parameters per connection
+var Verbose = false
+
 func Printf(logType LogType, format string, v ...any) {
 	rendered := fmt.Sprintf(format, v...)
 
@@ -0,0 +1,175 @@
+// Previews of files in the browser: Markdown rendered to HTML, highlighted source code and media viewers
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// How a file is previewed
+type Kind string
+
+const (
+	KindMarkdown Kind = "markdown"
+	// Source code and other text, highlighted with line numbers
+	KindCode  Kind = "code"
+	KindImage Kind = "image"
+	KindVideo Kind = "video"
+	KindAudio Kind = "audio"
+	KindPDF   Kind = "pdf"
+	// Files which cannot be previewed, only downloaded
+	KindNone Kind = "none"
+)
+
+// Largest text file rendered, larger ones are only linked
+const MaxTextSize = 2 << 20
+
+var ErrTooLarge = errors.New("file too large to preview")
+
+// Bytes looked at to tell text files without a known extension from binaries
+const sniffLength = 512
+
+// Gets how a file is previewed from its name, looking at its content for unknown extensions
+func KindOf(fsys fs.FS, name string) Kind {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == ".md" || ext == ".markdown" {
+		return KindMarkdown
+	}
+
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(ext), ";")
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return KindImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return KindVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return KindAudio
+	case mimeType == "application/pdf":
+		return KindPDF
+	case strings.HasPrefix(mimeType, "text/"), lexers.Match(path.Base(name)) != nil:
+		return KindCode
+	}
+
+	if isText(fsys, name) {
+		return KindCode
+	}
+
+	return KindNone
+}
+
+// Checks whether a file starts with text, as sniffed by [http.DetectContentType]
+func isText(fsys fs.FS, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	sniff := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false
+	}
+
+	return n > 0 && bytes.IndexByte(sniff[:n], 0) == -1 && strings.HasPrefix(http.DetectContentType(sniff[:n]), "text/")
+}
+
+// Reads a text file for previewing. Returns [ErrTooLarge] if it is larger than [MaxTextSize]
+func ReadText(fsys fs.FS, name string) ([]byte, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > MaxTextSize {
+		return nil, ErrTooLarge
+	}
+
+	return fs.ReadFile(fsys, name)
+}
+
+// Renders a Markdown or code file to HTML. Returns [ErrTooLarge] if it is larger than [MaxTextSize]
+func Render(fsys fs.FS, name string, kind Kind) (string, error) {
+	source, err := ReadText(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	if kind == KindMarkdown {
+		return RenderMarkdown(source)
+	}
+
+	return Highlight(name, source)
+}
+
+// Renders Markdown in GitHub's flavour. Raw HTML is left out and links with unsafe schemes
+// (e.g. "javascript:") are dropped, so the result is safe to embed in a page.
+func RenderMarkdown(source []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// Highlights source code with line numbers, each line has an "L<number>" anchor.
+// Styles come from [HighlightCSS], as the page's CSP does not allow inline styles.
+func Highlight(name string, source []byte) (string, error) {
+	lexer := lexers.Match(path.Base(name))
+	if lexer == nil {
+		lexer = lexers.Analyse(string(source))
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	tokens, err := lexer.Tokenise(nil, string(source))
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, style, tokens); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+var formatter = chromahtml.New(
+	chromahtml.WithClasses(true),
+	chromahtml.WithLineNumbers(true),
+	chromahtml.WithLinkableLineNumbers(true, "L"),
+	chromahtml.TabWidth(4),
+)
+
+var style = styles.Get("github")
+
+// Gets the style sheet of highlighted code
+func HighlightCSS() string {
+	var buf bytes.Buffer
+	formatter.WriteCSS(&buf, style)
+
+	return buf.String()
+}
@@ -0,0 +1,49 @@
+package preview
+
+import (
+	"errors"
+	"html"
+	"io/fs"
+	"path"
+)
+
+// Names of files shown below directory listings, in order of preference
+var ReadmeNames = []string{"README.md", "README.txt", "index.md"}
+
+// Rendered readme of a directory
+type Readme struct {
+	// Name of the file, e.g. "README.md"
+	Name string
+	HTML string
+}
+
+// Finds and renders the readme of a directory, Markdown with [RenderMarkdown] and text as it is.
+// Returns nil if the directory has none, or it is too large to render.
+func FindReadme(fsys fs.FS, dir string) (*Readme, error) {
+	for _, readmeName := range ReadmeNames {
+		name := path.Join(dir, readmeName)
+
+		info, err := fs.Stat(fsys, name)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		source, err := ReadText(fsys, name)
+		if errors.Is(err, ErrTooLarge) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		readme := &Readme{Name: readmeName}
+		if path.Ext(readmeName) == ".md" {
+			readme.HTML, err = RenderMarkdown(source)
+		} else {
+			readme.HTML = "<pre>" + html.EscapeString(string(source)) + "</pre>"
+		}
+
+		return readme, err
+	}
+
+	return nil, nil
+}
@@ -15,7 +15,9 @@ import (
 //
 // ignoreRedirect: a boolean that determines whether to ignore redirects from the target server.
 // Basically strips out Location header and return.
-func New(targetURL string, incHeaders bool, ignoreRedirect bool) (*httputil.ReverseProxy, error) {
+//
+// h2cUpstream: a boolean that determines whether to talk HTTP/2 without TLS (prior knowledge) to a "http://" target.
+func New(targetURL string, incHeaders bool, ignoreRedirect bool, h2cUpstream bool) (*httputil.ReverseProxy, error) {
 	target, err := url.Parse(targetURL)
 	if err != nil {
 		return nil, err
@@ -59,5 +61,15 @@ func New(targetURL string, incHeaders bool, ignoreRedirect bool) (*httputil.Reve
 		},
 	}
 
+	if h2cUpstream {
+		protocols := &http.Protocols{}
+		protocols.SetUnencryptedHTTP2(true)
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Protocols = protocols
+
+		reverseProxy.Transport = transport
+	}
+
 	return reverseProxy, nil
 }
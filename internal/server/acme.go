@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/logger"
+)
+
+const DefaultAcmeHTTPAddr = ":80"
+
+var DefaultAcmeCacheDir = filepath.Join(SelfSignedSSLPath, "acme")
+
+// Creates the ACME manager, issued certificates are put in the same certificate store used for TLS
+func (c *ServerConfig) setupAcme() (*acme.Manager, error) {
+	if c.Acme.CacheDir == "" {
+		c.Acme.CacheDir = DefaultAcmeCacheDir
+	}
+
+	manager, err := acme.New(c.Acme, c.certStore)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up ACME: %w", err)
+	}
+
+	return manager, nil
+}
+
+// Whether the plain HTTP server alongside HTTPS listens on the address HTTP-01 challenges are expected on
+func (c *ServerConfig) plainServerAnswersAcme() bool {
+	return c.HttpsEnabled && c.HttpAddr != "" && listensOn(c.HttpAddr, c.acmeHTTPAddr())
+}
+
+// Checks whether a server listening on listenAddr accepts connections to addr.
+// An empty host, 0.0.0.0 and [::] all listen on every interface, e.g. ":80" covers "0.0.0.0:80" and "127.0.0.1:80".
+func listensOn(listenAddr, addr string) bool {
+	listenHost, listenPort, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return listenAddr == addr
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	if normalisePort(listenPort) != normalisePort(port) {
+		return false
+	}
+
+	listenIP, ip := net.ParseIP(listenHost), net.ParseIP(host)
+
+	switch {
+	case listenHost == "" || (listenIP != nil && listenIP.IsUnspecified()):
+		return true
+	case listenIP != nil && ip != nil:
+		return listenIP.Equal(ip)
+	default:
+		return strings.EqualFold(listenHost, host)
+	}
+}
+
+// Gets the port number of a port or service name, e.g. "80" for "http"
+func normalisePort(port string) string {
+	if number, err := net.LookupPort("tcp", port); err == nil {
+		return strconv.Itoa(number)
+	}
+
+	return port
+}
+
+func (c *ServerConfig) acmeHTTPAddr() string {
+	if c.AcmeHTTPAddr == "" {
+		return DefaultAcmeHTTPAddr
+	}
+
+	return c.AcmeHTTPAddr
+}
+
+// Starts a plain HTTP server to answer HTTP-01 challenges
+func (c *ServerConfig) startAcmeChallengeServer(manager *acme.Manager) (*http.Server, error) {
+	challengeServer := &http.Server{
+		Addr:    c.acmeHTTPAddr(),
+		Handler: manager.HTTPHandler(http.NotFoundHandler()),
+	}
+
+	ln, err := net.Listen("tcp", challengeServer.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen for ACME challenges: %w", err)
+	}
+
+	go func() {
+		err := challengeServer.Serve(ln)
+		if !errors.Is(err, http.ErrServerClosed) {
+			c.reportServeError(fmt.Errorf("ACME challenge server: %w", err))
+		}
+	}()
+
+	logger.Printf(logger.LogNormal, "Listening for ACME HTTP-01 challenges on %s\n", challengeServer.Addr)
+
+	return challengeServer, nil
+}
@@ -1,9 +1,10 @@
 package assets
 
 import (
-	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
+	"sync"
 )
 
 type Asset struct {
@@ -16,12 +17,19 @@ const PrefixPath = "/_goserveass/"
 
 // Key is the file name to be served
 // Value is the file content in bytes
-var assets = make(map[string]Asset, 5)
+var (
+	assets   = make(map[string]Asset, 5)
+	assetsMu sync.RWMutex
+)
 
 func AssetsHandler(w http.ResponseWriter, r *http.Request) {
 	assetName := r.PathValue("asset")
 
-	if asset, ok := assets[assetName]; ok {
+	assetsMu.RLock()
+	asset, ok := assets[assetName]
+	assetsMu.RUnlock()
+
+	if ok {
 		asset.Serve(w)
 		return
 	}
@@ -29,18 +37,24 @@ func AssetsHandler(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
-// Add asset to the pool and return it's URI path
+// Add asset to the pool and return it's URI path.
+// The name is prefixed with a hash of the content, so adding the same asset again reuses its path.
 func (a Asset) AddAsset() (string, error) {
-	prefix := make([]byte, 4)
-	_, err := rand.Read(prefix)
-	if err != nil {
-		return "", err
-	}
+	hash := sha256.New()
+	hash.Write([]byte(a.Type))
+	hash.Write([]byte{0})
+	hash.Write(a.Content)
+
+	return a.AddAssetAt(hex.EncodeToString(hash.Sum(nil)[:8]) + a.Name), nil
+}
 
-	newName := hex.EncodeToString(prefix) + a.Name
-	assets[newName] = a
+// Add asset to the pool under a name, which may have several segments, and return it's URI path
+func (a Asset) AddAssetAt(name string) string {
+	assetsMu.Lock()
+	assets[name] = a
+	assetsMu.Unlock()
 
-	return PrefixPath + newName, nil
+	return PrefixPath + name
 }
 
 func (a Asset) Serve(w http.ResponseWriter) {
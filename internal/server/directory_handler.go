@@ -5,26 +5,80 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
+	"iter"
 	"net/http"
 
 	"github.com/ducng99/goserve/internal/files"
 	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/preview"
 	"github.com/ducng99/goserve/internal/tmpl/dirview"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
 )
 
 var ErrNonceGeneration = errors.New("failed to generate nonce")
 
 // Handler for directory requests.
 // Display an indexing page of contents in the directory
-func (c *ServerConfig) directoryHandler(w http.ResponseWriter, r *http.Request, dirPath string) {
+func (c *ServerConfig) directoryHandler(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, refs *files.GitRefs) {
+	listOptions, err := files.ParseListOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if listOptions.PerPage == 0 {
+		listOptions.PerPage = c.PageSize
+	}
+
 	// Get files in the provided directory
-	relativePath := files.RelativeRoot(c.RootDir, dirPath)
+	relativePath := files.RelativeRootFS(name)
+
+	var entries []files.DirEntry
+	var stream iter.Seq[files.DirEntry]
+	var page *files.Page
+
+	if listOptions.Sort == files.SortNone {
+		// Unsorted listings are rendered while the directory is read
+		entryStream, err := files.StreamEntriesFS(fsys, name, listOptions)
+		if err == nil {
+			defer entryStream.Close()
+			entries, err = entryStream.Next()
+		}
+		if err != nil {
+			http.Error(w, "Cannot get entries in the provided directory", http.StatusInternalServerError)
+			logger.Printf(logger.LogError, "%v\n", err)
+			return
+		}
 
-	entries, err := files.GetEntries(dirPath)
+		stream = streamEntries(fsys, name, entryStream)
+		page = &entryStream.Page
+	} else {
+		entries, err = files.GetEntries(fsys, name)
+		if err != nil {
+			http.Error(w, "Cannot get entries in the provided directory", http.StatusInternalServerError)
+			logger.Printf(logger.LogError, "%v\n", err)
+			return
+		}
+
+		var sortedPage files.Page
+		entries, sortedPage = listOptions.Paginate(listOptions.Apply(entries))
+		page = &sortedPage
+	}
+
+	files.CountItems(fsys, name, entries)
+
+	// Refs for the ref switcher, the listing is still useful without them
+	if refs != nil {
+		if refs.Refs, err = c.gitRepo.Refs(); err != nil {
+			logger.Printf(logger.LogError, "%v\n", err)
+		}
+	}
+
+	// The listing is still useful without the readme
+	readme, err := preview.FindReadme(fsys, name)
 	if err != nil {
-		http.Error(w, "Cannot get entries in the provided directory", http.StatusInternalServerError)
 		logger.Printf(logger.LogError, "%v\n", err)
-		return
 	}
 
 	// Generate nonce for CSP
@@ -35,15 +89,66 @@ func (c *ServerConfig) directoryHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Set some security headers
-	w.Header().Set("Content-Security-Policy", fmt.Sprintf("default-src 'none'; script-src 'nonce-%s'; connect-src 'self'; img-src 'self'; style-src 'nonce-%s'; frame-ancestors 'self'; form-action 'self';", nonce, nonce))
+	setPageHeaders(w, nonce)
+
+	listing := themes.Listing{
+		Path:    relativePath,
+		Entries: entries,
+		Stream:  stream,
+		Page:    page,
+		Refs:    refs,
+		Options: listOptions,
+		Query:   r.URL.Query(),
+		Units:   c.SizeUnits,
+		Readme:  readme,
+		// Only the files on disk are indexed
+		TextIndex: c.textIndex != nil && refs == nil,
+	}
+
+	if c.indexTemplate != nil {
+		if err := c.indexTemplate.Render(w, listing, nonce); err != nil {
+			http.Error(w, "Cannot render index template", http.StatusInternalServerError)
+			logger.Printf(logger.LogError, "%v\n", err)
+		}
+		return
+	}
+
+	dirview.Render(w, r, listing, nonce, c.DirViewTheme)
+}
+
+// Yields the rest of a streamed directory, counting items of sub-directories batch by batch
+func streamEntries(fsys fs.FS, name string, entryStream *files.EntryStream) iter.Seq[files.DirEntry] {
+	return func(yield func(files.DirEntry) bool) {
+		for {
+			entries, err := entryStream.Next()
+			if err != nil {
+				// The page is partly sent, it can only end early
+				logger.Printf(logger.LogError, "%v\n", err)
+				return
+			}
+
+			if len(entries) == 0 {
+				return
+			}
+
+			files.CountItems(fsys, name, entries)
+			for _, entry := range entries {
+				if !yield(entry) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Sets the CSP allowing the page's nonce, and other security headers of generated pages
+func setPageHeaders(w http.ResponseWriter, nonce string) {
+	w.Header().Set("Content-Security-Policy", fmt.Sprintf("default-src 'none'; script-src 'nonce-%s'; connect-src 'self'; img-src 'self'; media-src 'self'; font-src 'self'; frame-src 'self'; style-src 'nonce-%s'; frame-ancestors 'self'; form-action 'self';", nonce, nonce))
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("X-Frame-Options", "DENY")
 	w.Header().Set("X-XSS-Protection", "1; mode=block")
 	w.Header().Set("Referrer-Policy", "no-referrer")
 	w.Header().Set("Permissions-Policy", "accelerometer=(),ambient-light-sensor=(),autoplay=(),battery=(),camera=(),display-capture=(),document-domain=(),encrypted-media=(),fullscreen=(),gamepad=(),geolocation=(),gyroscope=(),magnetometer=(),microphone=(),midi=(),payment=(),picture-in-picture=(),publickey-credentials-get=(),speaker-selection=(),sync-xhr=(self),usb=(),screen-wake-lock=(),web-share=(),xr-spatial-tracking=()")
-
-	dirview.Render(w, r, relativePath, entries, nonce, c.DirViewTheme)
 }
 
 func generateNonce() (string, error) {
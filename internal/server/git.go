@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/logger"
+)
+
+// Opens the git repository and the tree of the default ref
+func (c *ServerConfig) setupGit() (fs.FS, error) {
+	repo, err := files.OpenGitRepo(c.GitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.GitRef == "" {
+		c.GitRef = "HEAD"
+	}
+
+	tree, err := repo.Tree(c.GitRef)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read git ref: %w", err)
+	}
+
+	logger.Printf(logger.LogNormal, "Serving git repository %s at %s (%s)\n", c.GitDir, c.GitRef, tree.Commit().ShortHash())
+
+	c.gitRepo = repo
+
+	return tree, nil
+}
+
+// Gets the file system to serve a request from.
+// For a git repository, it is the tree of the ref in the "ref" query parameter, or the default ref.
+func (c *ServerConfig) requestFS(r *http.Request) (fs.FS, *files.GitRefs, error) {
+	if c.gitRepo == nil {
+		return c.fsys, nil, nil
+	}
+
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		ref = c.GitRef
+	} else if ref != c.GitRef && ref != "HEAD" {
+		// Only the refs offered by the switcher, as reading the tree of any commit asked for is costly
+		refs, err := c.gitRepo.Refs()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !slices.Contains(refs, ref) {
+			return nil, nil, fmt.Errorf("%w: '%s'", files.ErrUnknownRef, ref)
+		}
+	}
+
+	tree, err := c.gitRepo.Tree(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.filter.FS(tree), &files.GitRefs{Current: ref, Default: c.GitRef}, nil
+}
@@ -0,0 +1,14 @@
+package server
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Wraps the handler to also accept HTTP/2 without TLS (h2c), either with prior knowledge or upgraded from HTTP/1.1.
+// Must wrap the whole handler chain, as upgrades hijack the connection.
+func H2cHandler(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Starts a HTTP/3 (QUIC) server on the UDP address in the background,
+// sharing the handler, TLS config and limits of the HTTPS server.
+// Returns the server and the address it is listening on.
+// Errors ending the server, other than closing it, are passed to onError, or logged if it is nil.
+func ListenHttp3(addr string, httpsServer *http.Server, onError func(error)) (*http3.Server, net.Addr, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h3Server := &http3.Server{
+		Addr:           addr,
+		Handler:        httpsServer.Handler,
+		TLSConfig:      http3.ConfigureTLSConfig(httpsServer.TLSConfig),
+		IdleTimeout:    httpsServer.IdleTimeout,
+		MaxHeaderBytes: httpsServer.MaxHeaderBytes,
+	}
+
+	go func() {
+		err := h3Server.Serve(conn)
+		if err == nil || errors.Is(err, http.ErrServerClosed) {
+			return
+		}
+
+		if onError != nil {
+			onError(fmt.Errorf("HTTP/3 server: %w", err))
+		} else {
+			logger.Printf(logger.LogError, "HTTP/3 server error: %v\n", err)
+		}
+	}()
+
+	return h3Server, conn.LocalAddr(), nil
+}
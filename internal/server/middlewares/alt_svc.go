@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Middleware to advertise HTTP/3 on the UDP port given by http3Port through the Alt-Svc header on HTTPS responses.
+// The port is looked up per request, as it may only be known once the server is listening.
+func AltSvcMiddleware(http3Port func() string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && r.ProtoMajor < 3 {
+			w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%s"; ma=86400`, http3Port()))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
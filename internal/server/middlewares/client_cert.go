@@ -0,0 +1,108 @@
+package middlewares
+
+import (
+	"crypto/x509"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Restricts requests under a path prefix to clients whose certificate matches one of the allowed names
+type ClientCertRule struct {
+	// Matched by whole path segments, "/secret" and "/secret/" both cover "/secret" and "/secret/file" but not "/secretary"
+	PathPrefix string
+	// Glob patterns (see [path.Match]) matched against the certificate's common name and SANs
+	Allowed []string
+}
+
+// Middleware to enforce client certificate rules.
+// The rule with the longest matching path prefix applies, requests not matching any rule are let through.
+func ClientCertMiddleware(rules []ClientCertRule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule := matchClientCertRule(rules, r.URL.Path)
+		if rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := VerifiedClientCert(r)
+		if cert == nil {
+			http.Error(w, "A client certificate is required", http.StatusForbidden)
+			return
+		}
+
+		if !rule.allows(cert) {
+			http.Error(w, "Client certificate is not allowed to access the given path", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Gets the verified client certificate of the request, or nil if there is none
+func VerifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return r.TLS.VerifiedChains[0][0]
+}
+
+func matchClientCertRule(rules []ClientCertRule, urlPath string) *ClientCertRule {
+	var matched *ClientCertRule
+
+	matchedLength := -1
+
+	for i := range rules {
+		prefix := strings.TrimSuffix(rules[i].PathPrefix, "/")
+		if !hasPathPrefix(urlPath, prefix) {
+			continue
+		}
+
+		if len(prefix) > matchedLength {
+			matched = &rules[i]
+			matchedLength = len(prefix)
+		}
+	}
+
+	return matched
+}
+
+// Checks whether urlPath is prefix or under it, prefix having no trailing "/"
+func hasPathPrefix(urlPath, prefix string) bool {
+	if !strings.HasPrefix(urlPath, prefix) {
+		return false
+	}
+
+	return len(urlPath) == len(prefix) || urlPath[len(prefix)] == '/'
+}
+
+func (rule *ClientCertRule) allows(cert *x509.Certificate) bool {
+	names := make([]string, 0, 1+len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.URIs)+len(cert.IPAddresses))
+	names = append(names, cert.Subject.CommonName)
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+
+	for _, pattern := range rule.Allowed {
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+
+			if matched, err := path.Match(pattern, name); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler to redirect all requests to the same URL on HTTPS.
+//
+// statusCode: the redirect status code, e.g. 301 or 308
+//
+// httpsPort: the port the HTTPS server listens on, omitted from the URL if it is 443
+func HttpsRedirectHandler(statusCode int, httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if splitHost, _, err := net.SplitHostPort(host); err == nil {
+			host = splitHost
+		}
+
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			// IPv6 addresses need brackets
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+
+		http.Redirect(w, r, target, statusCode)
+	})
+}
+
+// Middleware to set Strict-Transport-Security header on HTTPS responses
+func HstsMiddleware(maxAge time.Duration, next http.Handler) http.Handler {
+	headerValue := fmt.Sprintf("max-age=%d", int64(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", headerValue)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
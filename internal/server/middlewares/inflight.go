@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tracks requests being served, so the ones cut off by a shutdown can be reported
+type InFlight struct {
+	mu       sync.Mutex
+	requests map[*inFlightRequest]struct{}
+}
+
+type inFlightRequest struct {
+	remoteAddr string
+	method     string
+	path       string
+	start      time.Time
+	written    atomic.Int64
+	// Content-Length of the response, -1 if unknown
+	size atomic.Int64
+}
+
+// Snapshot of a request being served
+type InFlightRequest struct {
+	RemoteAddr   string
+	Method       string
+	Path         string
+	Start        time.Time
+	BytesWritten int64
+	// Content-Length of the response, -1 if unknown
+	Size int64
+}
+
+func NewInFlight() *InFlight {
+	return &InFlight{
+		requests: make(map[*inFlightRequest]struct{}),
+	}
+}
+
+// Middleware to register requests while they are being served
+func (f *InFlight) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := &inFlightRequest{
+			remoteAddr: r.RemoteAddr,
+			method:     r.Method,
+			path:       r.URL.Path,
+			start:      time.Now(),
+		}
+		request.size.Store(-1)
+
+		f.mu.Lock()
+		f.requests[request] = struct{}{}
+		f.mu.Unlock()
+
+		defer func() {
+			f.mu.Lock()
+			delete(f.requests, request)
+			f.mu.Unlock()
+		}()
+
+		next.ServeHTTP(&countingWriter{ResponseWriter: w, request: request}, r)
+	})
+}
+
+// Number of requests being served
+func (f *InFlight) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.requests)
+}
+
+// Snapshots of the requests being served
+func (f *InFlight) Requests() []InFlightRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := make([]InFlightRequest, 0, len(f.requests))
+	for request := range f.requests {
+		requests = append(requests, InFlightRequest{
+			RemoteAddr:   request.remoteAddr,
+			Method:       request.method,
+			Path:         request.path,
+			Start:        request.start,
+			BytesWritten: request.written.Load(),
+			Size:         request.size.Load(),
+		})
+	}
+
+	return requests
+}
+
+// Writer wrapper counting the response bytes of an in-flight request
+type countingWriter struct {
+	http.ResponseWriter
+	request     *inFlightRequest
+	wroteHeader bool
+}
+
+func (w *countingWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+
+		if size, ok := contentLength(w.Header()); ok {
+			w.request.size.Store(size)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.request.written.Add(int64(n))
+
+	return n, err
+}
+
+// Lets the underlying writer copy files with sendfile, the bytes are counted once copied
+func (w *countingWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+	if readerFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = readerFrom.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.request.written.Add(n)
+
+	return n, err
+}
+
+// Sends buffered data to the client, e.g. while a large listing is rendered
+func (w *countingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Allows [net/http.ResponseController] to reach the underlying writer
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func contentLength(header http.Header) (int64, bool) {
+	size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return 0, false
+	}
+
+	return size, true
+}
@@ -41,7 +41,11 @@ func LogConnectionMiddleware(next http.Handler) http.Handler {
 		case statusCode >= 400:
 			logType = logger.LogWarn
 		}
-		logger.Printf(logType, "%s [%d]: %s %s\n", r.RemoteAddr, statusCode, r.Method, r.URL.Path)
+		if cert := VerifiedClientCert(r); cert != nil {
+			logger.Printf(logType, "%s [%d]: %s %s (client: %s)\n", r.RemoteAddr, statusCode, r.Method, r.URL.Path, cert.Subject)
+		} else {
+			logger.Printf(logType, "%s [%d]: %s %s\n", r.RemoteAddr, statusCode, r.Method, r.URL.Path)
+		}
 
 		// Wait for the request to finish then continue logging
 		// We can't wait in the middle of a middleware because the request is still being processed
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/server/middlewares"
+)
+
+// Creates the handler for the plain HTTP server running alongside HTTPS.
+// Either serves the same routes, or redirects to HTTPS if HttpRedirect is set or client certificates are verified.
+func (c *ServerConfig) newPlainHandler(mux http.Handler, acmeManager *acme.Manager) http.Handler {
+	handler := mux
+
+	redirect := c.HttpRedirect
+	// Client certificates are only verified in the TLS handshake, plain HTTP would get around them
+	if redirect == 0 && c.ClientCAPath != "" {
+		redirect = http.StatusPermanentRedirect
+	}
+
+	if redirect != 0 {
+		handler = middlewares.LogConnectionMiddleware(middlewares.HttpsRedirectHandler(redirect, c.Port))
+	}
+
+	if acmeManager != nil && acmeManager.Challenge() == acme.ChallengeHTTP01 && c.plainServerAnswersAcme() {
+		handler = acmeManager.HTTPHandler(handler)
+	}
+
+	if c.H2cEnabled {
+		handler = H2cHandler(handler)
+	}
+
+	return handler
+}
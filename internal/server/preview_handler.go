@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/preview"
+	"github.com/ducng99/goserve/internal/tmpl/dirview"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+// Handler for file requests with the "view" query parameter.
+// Shows Markdown and code rendered in a page, and media in the browser's viewers.
+func (c *ServerConfig) previewHandler(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, refs *files.GitRefs) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		http.Error(w, "Cannot read file", http.StatusInternalServerError)
+		logger.Printf(logger.LogError, "%v\n", err)
+		return
+	}
+
+	p := themes.Preview{
+		Path:  files.RelativeRootFS(name),
+		Kind:  preview.KindOf(fsys, name),
+		Entry: files.DirEntry{DirEntry: fs.FileInfoToDirEntry(info)},
+		Refs:  refs,
+		Units: c.SizeUnits,
+	}
+
+	if p.Kind == preview.KindMarkdown || p.Kind == preview.KindCode {
+		if p.HTML, err = preview.Render(fsys, name, p.Kind); errors.Is(err, preview.ErrTooLarge) {
+			p.TooLarge = true
+		} else if err != nil {
+			http.Error(w, "Cannot render preview", http.StatusInternalServerError)
+			logger.Printf(logger.LogError, "%v\n", err)
+			return
+		}
+	}
+
+	nonce, err := generateNonce()
+	if err != nil {
+		http.Error(w, "Cannot generate nonce for CSP", http.StatusInternalServerError)
+		logger.Printf(logger.LogError, "%v\n", err)
+		return
+	}
+
+	setPageHeaders(w, nonce)
+
+	dirview.RenderPreview(w, r, p, nonce, c.DirViewTheme)
+}
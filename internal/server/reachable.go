@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/ducng99/goserve/internal/listener"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/mdp/qrterminal/v3"
+)
+
+// Logs every URL a listener on all interfaces can be reached at, e.g. the LAN IPs behind 0.0.0.0
+func logReachableURLs(protocol string, addr net.Addr) {
+	reachable := listener.ReachableAddrs(addr)
+	if len(reachable) == 1 && reachable[0] == addr {
+		return
+	}
+
+	for _, reachableAddr := range reachable {
+		logger.Printf(logger.LogNormal, "  Available on %s://%s\n", protocol, reachableAddr)
+	}
+}
+
+// Prints a QR code of the first LAN URL of the server, so it can be opened from a phone.
+// IPv4 addresses are preferred as they are easier to type and more widely routed on LANs.
+func printQRCode(httpServer *http.Server, listeners []net.Listener) {
+	var lanAddr *net.TCPAddr
+
+	for _, ln := range listeners {
+		for _, addr := range listener.ReachableAddrs(ln.Addr()) {
+			tcpAddr, ok := addr.(*net.TCPAddr)
+			if !ok || tcpAddr.IP.IsLoopback() {
+				continue
+			}
+
+			if lanAddr == nil || (lanAddr.IP.To4() == nil && tcpAddr.IP.To4() != nil) {
+				lanAddr = tcpAddr
+			}
+		}
+	}
+
+	if lanAddr == nil {
+		logger.Printf(logger.LogWarn, "No LAN address to show a QR code for\n")
+		return
+	}
+
+	url := serverProtocol(httpServer) + "://" + lanAddr.String()
+
+	logger.Printf(logger.LogNormal, "Scan to open %s\n", url)
+	qrterminal.GenerateHalfBlock(url, qrterminal.L, os.Stderr)
+}
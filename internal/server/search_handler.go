@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/tmpl/dirview"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+// Search limits used when the config leaves them at zero
+const (
+	DefaultSearchDepth   = 16
+	DefaultSearchResults = 1000
+	DefaultSearchTimeout = 10 * time.Second
+)
+
+// A match in JSON search results
+type searchResultJSON struct {
+	// URL path of the entry, e.g. "/docs/readme.md"
+	Path    string     `json:"path"`
+	Name    string     `json:"name"`
+	Dir     bool       `json:"dir"`
+	Size    int64      `json:"size"`
+	ModTime *time.Time `json:"mtime,omitempty"`
+}
+
+// Last line of JSON search results
+type searchSummaryJSON struct {
+	Done    bool             `json:"done"`
+	Count   int              `json:"count"`
+	Stopped files.SearchStop `json:"stopped,omitempty"`
+}
+
+// Handler for directory requests with the "search" query parameter.
+// Searches names under the directory, results are sent as they are found.
+func (c *ServerConfig) searchHandler(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, refs *files.GitRefs) {
+	search, err := files.NewSearch(fsys, name, r.URL.Query().Get("search"), c.searchOptions())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wantsJSON(r) {
+		c.searchJSON(w, r, search, files.RelativeRootFS(name))
+		return
+	}
+
+	nonce, err := generateNonce()
+	if err != nil {
+		http.Error(w, "Cannot generate nonce for CSP", http.StatusInternalServerError)
+		logger.Printf(logger.LogError, "%v\n", err)
+		return
+	}
+
+	setPageHeaders(w, nonce)
+
+	listing := themes.SearchListing{
+		Path:      files.RelativeRootFS(name),
+		Search:    search,
+		Results:   search.Results(r.Context()),
+		Refs:      refs,
+		Units:     c.SizeUnits,
+		TextIndex: c.textIndex != nil && refs == nil,
+	}
+
+	dirview.RenderSearch(w, r, listing, nonce, c.DirViewTheme)
+}
+
+// Writes results as JSON lines, one match per line and a summary at the end
+func (c *ServerConfig) searchJSON(w http.ResponseWriter, r *http.Request, search *files.Search, dirPath string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	encoder := json.NewEncoder(w)
+	flusher := http.NewResponseController(w)
+
+	for result := range search.Results(r.Context()) {
+		line := searchResultJSON{
+			Path: path.Join(dirPath, result.Path),
+			Name: result.Entry.Name(false),
+			Dir:  result.Entry.IsDir(),
+		}
+
+		if info, err := result.Entry.Info(); err == nil {
+			modTime := info.ModTime()
+			line.ModTime = &modTime
+			if !info.IsDir() {
+				line.Size = info.Size()
+			}
+		}
+
+		if err := encoder.Encode(line); err != nil {
+			return
+		}
+
+		if err := flusher.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+	}
+
+	encoder.Encode(searchSummaryJSON{Done: true, Count: search.Count, Stopped: search.Stopped})
+}
+
+func (c *ServerConfig) searchOptions() files.SearchOptions {
+	opts := files.SearchOptions{MaxDepth: c.SearchMaxDepth, MaxResults: c.SearchMaxResults, Timeout: c.SearchTimeout}
+
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultSearchDepth
+	}
+	if opts.MaxResults == 0 {
+		opts.MaxResults = DefaultSearchResults
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultSearchTimeout
+	}
+
+	return opts
+}
+
+// Checks whether the client asked for JSON with ?format=json or the Accept header
+func wantsJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "json"
+	}
+
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, "application/x-ndjson")
+}
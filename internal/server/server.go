@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
@@ -12,84 +15,406 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ducng99/goserve/internal/acme"
 	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/fulltext"
+	"github.com/ducng99/goserve/internal/listener"
 	"github.com/ducng99/goserve/internal/logger"
 	"github.com/ducng99/goserve/internal/proxy"
 	"github.com/ducng99/goserve/internal/server/assets"
 	"github.com/ducng99/goserve/internal/server/middlewares"
 	"github.com/ducng99/goserve/internal/ssl"
+	"github.com/ducng99/goserve/internal/thumbnail"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes/custom"
 )
 
 var SelfSignedSSLPath = filepath.Join(os.TempDir(), "goserve")
 
-// Starts web server
+const DefaultShutdownTimeout = 3 * time.Second
+
+// Entries per directory listing page by default
+const DefaultPageSize = 1000
+
+// Starts web server, and shuts it down on SIGINT or SIGTERM
 func (c *ServerConfig) StartServer() {
-	// Set up routes
-	mux := c.NewServeMux()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Setup HTTPS if enabled
-	c.SetupSSL()
+	if err := c.Start(ctx); err != nil {
+		logger.Fatalf("Cannot start server: %v\n", err)
+	}
 
-	// Start server
-	listenAddr := net.JoinHostPort(c.Host, c.Port)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	httpServer := &http.Server{
-		Addr:    listenAddr,
-		Handler: mux,
+	var sig os.Signal
+	select {
+	case sig = <-sigChan:
+	case err := <-c.serveErrs:
+		logger.Fatalf("HTTP server error: %v\n", err)
 	}
 
-	go func() {
-		var err error
+	shutdownTimeout := c.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
 
-		if c.HttpsEnabled {
-			err = httpServer.ListenAndServeTLS(c.CertPath, c.KeyPath)
-		} else {
-			err = httpServer.ListenAndServe()
+	if sig == syscall.SIGTERM && c.inFlight != nil {
+		shutdownTimeout = c.DrainTimeout
+		logger.Printf(logger.LogNormal, "Terminated. Draining %d in-flight requests for up to %s...\n", c.inFlight.Len(), shutdownTimeout)
+	} else {
+		logger.Printf(logger.LogNormal, "Interrupted. Shutting down...\n")
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	if err := c.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		logger.Fatalf("HTTP shutdown error: %v\n", err)
+	}
+
+	logger.Printf(logger.LogNormal, "Server stopped\n")
+}
+
+// Binds all listeners and starts serving in the background.
+// Background tasks such as certificate renewals stop when ctx is done, use Shutdown to stop serving.
+func (c *ServerConfig) Start(ctx context.Context) error {
+	c.serveErrs = make(chan error, 1)
+
+	// Bind first, so the port actually in use is known to redirects and Alt-Svc
+	listeners, err := c.listen(c.listenAddrs())
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.listeners = listeners
+
+	for _, ln := range listeners {
+		if ln.Addr().Network() == "tcp" {
+			_, c.Port, _ = net.SplitHostPort(ln.Addr().String())
+			break
 		}
+	}
+	c.mu.Unlock()
+
+	if c.MaxConns > 0 {
+		c.connLimiter = listener.NewLimiter(c.MaxConns)
+	}
 
-		if !errors.Is(err, http.ErrServerClosed) {
-			logger.Fatalf("HTTP server error: %v\n", err)
+	// Set up routes, unless Handler already did
+	mux, err := c.Handler()
+	if err != nil {
+		c.closeListeners()
+		return err
+	}
+
+	c.runTextIndex(ctx)
+
+	// Setup HTTPS if enabled
+	if err := c.SetupSSL(); err != nil {
+		c.closeListeners()
+		return err
+	}
+
+	var acmeManager *acme.Manager
+	if c.AcmeEnabled {
+		acmeManager, err = c.setupAcme()
+		if err != nil {
+			c.closeListeners()
+			return err
 		}
-		logger.Printf(logger.LogNormal, "Interrupted. Shutting down...\n")
-	}()
+	}
 
-	protocol := "http"
+	// Start servers
+	httpServer := c.newHTTPServer(mux)
 
 	if c.HttpsEnabled {
-		protocol = "https"
+		httpServer.TLSConfig, err = c.newTLSConfig(ctx, acmeManager)
+		if err != nil {
+			c.closeListeners()
+			return err
+		}
+
+		if logger.Verbose {
+			httpServer.ConnState = logNegotiatedTLS
+		}
+	} else if c.H2cEnabled {
+		httpServer.Handler = H2cHandler(mux)
 	}
 
-	serverURL := protocol + "://" + listenAddr
+	c.servers = []shutdowner{httpServer}
+	c.runServer(httpServer, listeners)
 
-	logger.Printf(logger.LogNormal, "Started goserve %s server (%s)\n", strings.ToUpper(protocol), serverURL)
+	if c.QRCode {
+		printQRCode(httpServer, listeners)
+	}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	// HTTP/3 shares the handlers and certificates with the HTTPS server, on the same TCP addresses but over UDP
+	if c.HttpsEnabled && c.Http3Enabled {
+		for _, ln := range listeners {
+			if ln.Addr().Network() != "tcp" {
+				continue
+			}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer shutdownCancel()
+			h3Server, h3Addr, err := ListenHttp3(ln.Addr().String(), httpServer, c.reportServeError)
+			if err != nil {
+				c.Close()
+				return fmt.Errorf("cannot start HTTP/3 server: %w", err)
+			}
 
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		logger.Fatalf("HTTP shutdown error: %v\n", err)
+			c.servers = append(c.servers, h3Server)
+			logger.Printf(logger.LogNormal, "Started goserve HTTP/3 server (https://%s)\n", h3Addr)
+		}
+	} else if c.Http3Enabled {
+		logger.Printf(logger.LogWarn, "HTTP/3 is ignored as HTTPS is disabled\n")
 	}
 
-	logger.Printf(logger.LogNormal, "Server stopped\n")
+	// Plain HTTP server alongside HTTPS
+	if c.HttpsEnabled && c.HttpAddr != "" {
+		plainListeners, err := c.listen([]listener.Address{{Network: listener.NetworkTCP, Addr: c.HttpAddr}})
+		if err != nil {
+			c.Close()
+			return err
+		}
+
+		plainServer := c.newHTTPServer(c.newPlainHandler(mux, acmeManager))
+
+		c.servers = append(c.servers, plainServer)
+		c.runServer(plainServer, plainListeners)
+	} else if c.HttpAddr != "" {
+		logger.Printf(logger.LogWarn, "Plain HTTP address %s is ignored as HTTPS is disabled\n", c.HttpAddr)
+	}
+
+	// Challenges are answered by the servers, so certificates are requested after they started
+	if acmeManager != nil {
+		if acmeManager.Challenge() == acme.ChallengeHTTP01 && !c.plainServerAnswersAcme() {
+			challengeServer, err := c.startAcmeChallengeServer(acmeManager)
+			if err != nil {
+				c.Close()
+				return err
+			}
+
+			c.servers = append(c.servers, challengeServer)
+		}
+
+		acmeManager.Start(ctx)
+	}
+
+	return nil
+}
+
+// Reports errors of servers which stopped unexpectedly after Start
+func (c *ServerConfig) ServeErrors() <-chan error {
+	return c.serveErrs
+}
+
+// Addresses the server is listening on, after Start
+func (c *ServerConfig) Addrs() []net.Addr {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	addrs := make([]net.Addr, 0, len(c.listeners))
+	for _, ln := range c.listeners {
+		addrs = append(addrs, ln.Addr())
+	}
+
+	return addrs
+}
+
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// Stops accepting connections and waits for in-flight requests until ctx is done,
+// then closes the remaining connections and returns the context error
+func (c *ServerConfig) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	for _, server := range c.servers {
+		if err := server.Shutdown(ctx); err != nil && ctx.Err() == nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if ctx.Err() == nil {
+		return errors.Join(errs...)
+	}
+
+	logger.Printf(logger.LogWarn, "Shutdown timed out, closing remaining connections\n")
+
+	if c.inFlight != nil {
+		for _, request := range c.inFlight.Requests() {
+			written := fmt.Sprintf("%d bytes", request.BytesWritten)
+			if request.Size >= 0 {
+				written = fmt.Sprintf("%d of %d bytes", request.BytesWritten, request.Size)
+			}
+
+			logger.Printf(logger.LogWarn, "%s Cut off: %s %s - written %s - %s\n", request.RemoteAddr, request.Method, request.Path, written, time.Since(request.Start))
+		}
+	}
+
+	c.Close()
+
+	return errors.Join(append(errs, ctx.Err())...)
+}
+
+// Closes all servers and connections immediately
+func (c *ServerConfig) Close() error {
+	var errs []error
+
+	for _, server := range c.servers {
+		if err := server.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Listeners not handed to a server yet
+	if len(c.servers) == 0 {
+		c.closeListeners()
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *ServerConfig) closeListeners() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, ln := range c.listeners {
+		ln.Close()
+	}
+}
+
+// Creates a server with the configured timeouts and limits
+func (c *ServerConfig) newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+}
+
+// Addresses to listen on, defaults to Host:Port over TCP
+func (c *ServerConfig) listenAddrs() []listener.Address {
+	if len(c.ListenAddrs) > 0 {
+		return c.ListenAddrs
+	}
+
+	return []listener.Address{{Network: listener.NetworkTCP, Addr: net.JoinHostPort(c.Host, c.Port), PortFallback: c.PortFallback}}
+}
+
+// Creates listeners for all addresses, closing them all if any cannot be listened on
+func (c *ServerConfig) listen(addrs []listener.Address) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addrs))
+
+	for _, addr := range addrs {
+		addrListeners, err := listener.Listen(addr, listener.Options{SocketMode: c.SocketMode})
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+
+			return nil, fmt.Errorf("cannot listen on %s: %w", addr, err)
+		}
+
+		if addr.PortFallback {
+			_, wantedPort, _ := net.SplitHostPort(addr.Addr)
+			_, port, _ := net.SplitHostPort(addrListeners[0].Addr().String())
+
+			if port != wantedPort {
+				logger.Printf(logger.LogWarn, "Port %s is in use, using port %s instead\n", wantedPort, port)
+			}
+		}
+
+		listeners = append(listeners, addrListeners...)
+	}
+
+	return listeners, nil
+}
+
+// Serves on the listeners in the background, using HTTPS if the server has a TLS config
+func (c *ServerConfig) runServer(httpServer *http.Server, listeners []net.Listener) {
+	protocol := serverProtocol(httpServer)
+
+	for _, ln := range listeners {
+		if c.connLimiter != nil {
+			ln = c.connLimiter.Wrap(ln)
+		}
+
+		serverURL := protocol + "://" + ln.Addr().String()
+		if ln.Addr().Network() == "unix" {
+			serverURL = protocol + "+unix:" + ln.Addr().String()
+		}
+
+		if httpServer.TLSConfig != nil {
+			// Use the TLS config as is instead of a clone made by ServeTLS, so rotated session ticket keys apply
+			ln = tls.NewListener(ln, httpServer.TLSConfig)
+		}
+
+		go func() {
+			err := httpServer.Serve(ln)
+			if !errors.Is(err, http.ErrServerClosed) {
+				c.reportServeError(err)
+			}
+		}()
+
+		logger.Printf(logger.LogNormal, "Started goserve %s server (%s)\n", strings.ToUpper(protocol), serverURL)
+		logReachableURLs(protocol, ln.Addr())
+	}
+}
+
+// Reports the first unexpected server error, later ones are only logged
+func (c *ServerConfig) reportServeError(err error) {
+	select {
+	case c.serveErrs <- err:
+	default:
+		logger.Printf(logger.LogError, "HTTP server error: %v\n", err)
+	}
+}
+
+func serverProtocol(httpServer *http.Server) string {
+	if httpServer.TLSConfig != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Gets the handler serving the configured routes, built by the first call and shared with the servers of Start.
+// Links in listings and assets are absolute, so the handler must be mounted at "/" rather than under a prefix.
+func (c *ServerConfig) Handler() (http.Handler, error) {
+	if c.handler == nil {
+		mux, err := c.NewServeMux()
+		if err != nil {
+			return nil, err
+		}
+		c.handler = mux
+	}
+
+	return c.handler, nil
 }
 
 // NewServeMux creates a new HTTP ServeMux with configured routes
-func (c *ServerConfig) NewServeMux() *http.ServeMux {
+func (c *ServerConfig) NewServeMux() (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 	var routeHandler http.Handler
 
 	if c.ProxyToAddr != "" {
-		proxyHandler, err := proxy.New(c.ProxyToAddr, c.ProxyHeadersEnabled, c.ProxyIgnoreRedirect)
+		proxyHandler, err := proxy.New(c.ProxyToAddr, c.ProxyHeadersEnabled, c.ProxyIgnoreRedirect, c.ProxyH2c)
 		if err != nil {
-			logger.Fatalf("Error creating reverse proxy handler: %v\n", err)
+			return nil, fmt.Errorf("cannot create reverse proxy handler: %w", err)
 		}
 
 		routeHandler = proxyHandler
 	} else {
+		if err := c.setupFS(); err != nil {
+			return nil, err
+		}
+
 		routeHandler = http.Handler(http.HandlerFunc(c.routeHandlerFunc))
 	}
 
@@ -97,17 +422,249 @@ func (c *ServerConfig) NewServeMux() *http.ServeMux {
 		routeHandler = middlewares.CorsMiddleware(routeHandler)
 	}
 
+	if len(c.ClientCertRules) > 0 {
+		// Without a CA no client certificate is verified, every request under the rules would be refused
+		if c.ClientCAPath == "" {
+			return nil, errors.New("client certificate rules require a client CA")
+		}
+
+		routeHandler = middlewares.ClientCertMiddleware(c.ClientCertRules, routeHandler)
+	}
+
+	if c.Http3Enabled {
+		routeHandler = middlewares.AltSvcMiddleware(c.boundPort, routeHandler)
+	}
+
+	if c.HstsMaxAge > 0 {
+		routeHandler = middlewares.HstsMiddleware(c.HstsMaxAge, routeHandler)
+	}
+
+	// User middlewares, the first one runs first
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		routeHandler = c.Middlewares[i](routeHandler)
+	}
+
+	if c.DrainTimeout > 0 {
+		if c.inFlight == nil {
+			c.inFlight = middlewares.NewInFlight()
+		}
+		routeHandler = c.inFlight.Middleware(routeHandler)
+	}
+
 	routeHandler = middlewares.LogConnectionMiddleware(routeHandler)
 	mux.Handle("/", routeHandler)
-	mux.HandleFunc(assets.PrefixPath+"{asset}", assets.AssetsHandler)
+	mux.HandleFunc(assets.PrefixPath+"{asset...}", assets.AssetsHandler)
+
+	return mux, nil
+}
+
+// Gets the port HTTP/3 is served on, the one picked by Start for port 0
+func (c *ServerConfig) boundPort() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.Port
+}
+
+// Keeps the text index up to date in the background until ctx is done, from the first call on
+func (c *ServerConfig) runTextIndex(ctx context.Context) {
+	if c.textIndex == nil {
+		return
+	}
+
+	c.textIndexOnce.Do(func() {
+		go c.textIndex.Run(ctx)
+	})
+}
+
+// Sets up the file system to serve, with hidden paths filtered out
+func (c *ServerConfig) setupFS() error {
+	if c.fsys != nil {
+		return nil
+	}
+
+	if c.SizeUnits != "" && !c.SizeUnits.Valid() {
+		return fmt.Errorf("unknown size units '%s'", c.SizeUnits)
+	}
+
+	if c.PageSize < 0 || c.PageSize > files.MaxPerPage {
+		return fmt.Errorf("page size must be between 0 and %d", files.MaxPerPage)
+	}
+
+	if c.SearchMaxDepth < 0 || c.SearchMaxResults < 0 || c.SearchTimeout < 0 {
+		return errors.New("search limits cannot be negative")
+	}
+
+	if c.TextIndexMaxSize < 0 || c.TextIndexInterval < 0 {
+		return errors.New("text index limits cannot be negative")
+	}
+
+	if c.Symlinks != "" && !c.Symlinks.Valid() {
+		return fmt.Errorf("unknown symlink policy '%s'", c.Symlinks)
+	}
+
+	filter, err := files.NewFilter(files.FilterOptions{HideDotfiles: c.HideDotfiles, Exclude: c.Exclude, IgnoreFiles: c.IgnoreFiles})
+	if err != nil {
+		return fmt.Errorf("cannot set up exclude patterns: %w", err)
+	}
+	c.filter = filter
+
+	fsys, err := c.openFS()
+	if err != nil {
+		return err
+	}
+	c.fsys = filter.FS(fsys)
+
+	c.thumbnails = thumbnail.NewCache(thumbnail.DefaultCacheSize)
+
+	if c.IndexTemplate != "" {
+		if c.indexTemplate, err = custom.Load(c.IndexTemplate); err != nil {
+			return err
+		}
+	}
+
+	if c.TextIndex {
+		c.textIndex = fulltext.New(c.fsys, fulltext.Options{MaxFileSize: c.TextIndexMaxSize, Interval: c.TextIndexInterval, Dirs: c.watchedDirs()})
+	}
+
+	return nil
+}
+
+// Opens the file system to serve: FS if set, a tree of GitDir, otherwise RootDir layered over OverlayDirs
+func (c *ServerConfig) openFS() (fs.FS, error) {
+	if c.FS != nil {
+		return c.FS, nil
+	}
+
+	if c.GitDir != "" {
+		return c.setupGit()
+	}
+
+	if len(c.OverlayDirs) == 0 {
+		return c.openRoot(c.RootDir)
+	}
+
+	rootDirs := append([]string{c.RootDir}, c.OverlayDirs...)
+	names := layerNames(rootDirs)
+
+	layers := make([]files.Layer, 0, len(rootDirs))
+	for i, rootDir := range rootDirs {
+		fsys, err := c.openRoot(rootDir)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, files.Layer{Name: names[i], FS: fsys})
+	}
+
+	return files.NewUnionFS(layers...), nil
+}
+
+// Names layers by the last element of their root, with as many parents as needed to tell them apart,
+// e.g. "a/public" and "b/public"
+func layerNames(rootDirs []string) []string {
+	parts := make([][]string, len(rootDirs))
+	for i, rootDir := range rootDirs {
+		if absDir, err := filepath.Abs(rootDir); err == nil {
+			rootDir = absDir
+		}
+		parts[i] = strings.Split(filepath.ToSlash(rootDir), "/")
+	}
+
+	suffix := func(i, n int) string {
+		return strings.Join(parts[i][max(len(parts[i])-n, 0):], "/")
+	}
+
+	names := make([]string, len(rootDirs))
+	for i := range rootDirs {
+		for n := 1; ; n++ {
+			names[i] = suffix(i, n)
+			if n >= len(parts[i]) {
+				break
+			}
+
+			unique := true
+			for j := range rootDirs {
+				if j != i && suffix(j, n) == names[i] {
+					unique = false
+					break
+				}
+			}
+			if unique {
+				break
+			}
+		}
+
+		if names[i] == "" {
+			names[i] = "/"
+		}
+	}
+
+	return names
+}
+
+// Gets the root directories on disk, in the order of their layers, for the text index to watch.
+// Archives do not change, and FS or a git tree cannot be watched.
+func (c *ServerConfig) watchedDirs() []string {
+	if c.FS != nil || c.GitDir != "" {
+		return nil
+	}
+
+	var dirs []string
+	for _, rootDir := range append([]string{c.RootDir}, c.OverlayDirs...) {
+		if info, err := os.Stat(rootDir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		if absDir, err := filepath.Abs(rootDir); err == nil {
+			dirs = append(dirs, absDir)
+		}
+	}
+
+	return dirs
+}
+
+// Opens a root to serve, an archive if the path is a file, otherwise a directory
+func (c *ServerConfig) openRoot(rootDir string) (fs.FS, error) {
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read root directory: %w", err)
+	}
+
+	if info.IsDir() {
+		osfs := files.NewOSFS(rootDir)
+		if c.Symlinks != "" {
+			osfs = osfs.WithSymlinks(c.Symlinks, c.SymlinkAllowDirs...)
+		}
 
-	return mux
+		return osfs, nil
+	}
+
+	archive, err := files.OpenArchive(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Printf(logger.LogNormal, "Serving archive %s\n", rootDir)
+
+	return archive, nil
 }
 
 // Handler for all requests.
 // Serves files or display directory index
 func (c *ServerConfig) routeHandlerFunc(w http.ResponseWriter, r *http.Request) {
-	sanitisedPath, err := files.SanitisePath(c.RootDir, r.URL.Path)
+	fsys, refs, err := c.requestFS(r)
+	if err != nil {
+		if errors.Is(err, files.ErrUnknownRef) {
+			http.Error(w, "Ref not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Cannot read git repository", http.StatusInternalServerError)
+			logger.Printf(logger.LogError, "%v\n", err)
+		}
+		return
+	}
+
+	name, err := files.SanitisePathFS(fsys, r.URL.Path)
 	if err != nil {
 		switch {
 		case errors.Is(err, files.ErrorSanitiseNotExists):
@@ -121,7 +678,7 @@ func (c *ServerConfig) routeHandlerFunc(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	pathType, err := files.GetPathType(sanitisedPath)
+	pathType, err := files.GetPathType(fsys, name)
 	if err != nil {
 		http.Error(w, "Cannot get path type", http.StatusInternalServerError)
 		logger.Printf(logger.LogError, "%v\n", err)
@@ -130,53 +687,98 @@ func (c *ServerConfig) routeHandlerFunc(w http.ResponseWriter, r *http.Request)
 
 	switch pathType {
 	case files.PathTypeFile:
-		http.ServeFile(w, r, sanitisedPath)
+		switch query := r.URL.Query(); {
+		case query.Get("thumb") != "":
+			c.thumbnailHandler(w, r, fsys, name, refs)
+		case query.Get("view") != "":
+			c.previewHandler(w, r, fsys, name, refs)
+		default:
+			http.ServeFileFS(w, r, fsys, name)
+		}
 	case files.PathTypeDirectory:
-		c.directoryHandler(w, r, sanitisedPath)
+		switch query := r.URL.Query(); {
+		case query.Get("search") != "" && query.Get("content") != "":
+			c.textSearchHandler(w, r, fsys, name, refs)
+		case query.Get("search") != "":
+			c.searchHandler(w, r, fsys, name, refs)
+		default:
+			c.directoryHandler(w, r, fsys, name, refs)
+		}
 	default:
 		http.Error(w, "Path type not handled correctly", http.StatusInternalServerError)
 	}
 }
 
 // Checks if HTTPS is enabled and sets up SSL keys if necessary
-func (c *ServerConfig) SetupSSL() {
-	if c.HttpsEnabled {
-		if c.CertPath != "" && c.KeyPath != "" {
-			f, err := os.Open(c.CertPath)
+func (c *ServerConfig) SetupSSL() error {
+	// Per-host certificates, ACME and client certificates imply HTTPS
+	if len(c.SNICerts) > 0 || c.AcmeEnabled || c.ClientCAPath != "" {
+		c.HttpsEnabled = true
+	}
+
+	if !c.HttpsEnabled {
+		return nil
+	}
+
+	if c.CertPath != "" && c.KeyPath != "" {
+		f, err := os.Open(c.CertPath)
+		if err != nil {
+			return fmt.Errorf("cannot read cert file '%s': %w", c.CertPath, err)
+		}
+		f.Close()
+
+		f, err = os.Open(c.KeyPath)
+		if err != nil {
+			return fmt.Errorf("cannot read key file at '%s': %w", c.KeyPath, err)
+		}
+		f.Close()
+	} else if c.CertPath == "" && c.KeyPath == "" {
+		certPath, privKeyPath, exists := ssl.KeysExist(SelfSignedSSLPath)
+
+		if exists {
+			logger.Printf(logger.LogNormal, "Using previous self-signed SSL certificate\n")
+		} else {
+			keyPair, err := ssl.NewKeys(365 * 24 * time.Hour)
 			if err != nil {
-				logger.Fatalf("Cannot read cert file '%s': %v\n", c.CertPath, err)
+				return fmt.Errorf("cannot generate SSL keys: %w", err)
 			}
-			f.Close()
 
-			f, err = os.Open(c.KeyPath)
+			certPath, privKeyPath, err = keyPair.Save(SelfSignedSSLPath)
 			if err != nil {
-				logger.Fatalf("Cannot read key file at '%s': %v\n", c.KeyPath, err)
+				return err
 			}
-			f.Close()
-		} else if c.CertPath == "" && c.KeyPath == "" {
-			certPath, privKeyPath, exists := ssl.KeysExist(SelfSignedSSLPath)
-
-			if exists {
-				logger.Printf(logger.LogNormal, "Using previous self-signed SSL certificate\n")
-			} else {
-				keyPair, err := ssl.NewKeys(365 * 24 * time.Hour)
-				if err != nil {
-					logger.Fatalf("Error generating SSL keys: %v\n", err)
-				}
 
-				certPath, privKeyPath, err = keyPair.Save(SelfSignedSSLPath)
-				if err != nil {
-					logger.Fatalf("%v\n", err)
-				}
+			logger.Printf(logger.LogNormal, "Generated SSL key fingerprint:\n% X\n", keyPair.Fingerprint)
+		}
 
-				logger.Printf(logger.LogNormal, "Generated SSL key fingerprint:\n% X\n", keyPair.Fingerprint)
-			}
+		c.CertPath = certPath
+		c.KeyPath = privKeyPath
+	} else {
+		return errors.New("both cert and key paths must be provided, or both must be empty to use a self-signed certificate")
+	}
 
-			c.CertPath = certPath
-			c.KeyPath = privKeyPath
-		} else {
-			// Cobra already handles this but just in case
-			logger.Fatalf("Both cert and key paths must be provided. Or both must be empty to use a self-signed certificate\n")
+	return c.setupCertStore()
+}
+
+// Loads the default certificate and per-host certificates into the certificate store
+func (c *ServerConfig) setupCertStore() error {
+	c.certStore = ssl.NewCertStore()
+
+	defaultCert, err := tls.LoadX509KeyPair(c.CertPath, c.KeyPath)
+	if err != nil {
+		return fmt.Errorf("cannot load certificate '%s' and key '%s': %w", c.CertPath, c.KeyPath, err)
+	}
+	c.certStore.SetDefault(&defaultCert)
+
+	for _, sniCert := range c.SNICerts {
+		cert, err := tls.LoadX509KeyPair(sniCert.CertPath, sniCert.KeyPath)
+		if err != nil {
+			return fmt.Errorf("cannot load certificate '%s' and key '%s' for host '%s': %w", sniCert.CertPath, sniCert.KeyPath, sniCert.Host, err)
 		}
+
+		c.certStore.Set(sniCert.Host, &cert)
+		logger.Printf(logger.LogNormal, "Loaded certificate for host '%s'\n", sniCert.Host)
 	}
+
+	return nil
 }
@@ -1,15 +1,136 @@
 package server
 
+import (
+	"crypto/tls"
+	"io/fs"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/fulltext"
+	"github.com/ducng99/goserve/internal/listener"
+	"github.com/ducng99/goserve/internal/server/middlewares"
+	"github.com/ducng99/goserve/internal/ssl"
+	"github.com/ducng99/goserve/internal/thumbnail"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes/custom"
+)
+
 type ServerConfig struct {
-	Host                string
-	Port                string
-	RootDir             string
-	CorsEnabled         bool
-	DirViewTheme        string
+	Host         string
+	Port         string
+	PortFallback bool
+	ListenAddrs  []listener.Address
+	SocketMode   fs.FileMode
+	QRCode       bool
+	RootDir      string
+	CorsEnabled  bool
+	DirViewTheme string
+	// Template file or theme directory of listings, overriding DirViewTheme (see package custom)
+	IndexTemplate       string
 	HttpsEnabled        bool
 	CertPath            string
 	KeyPath             string
+	SNICerts            []SNICert
+	HttpAddr            string
+	HttpRedirect        int
+	HstsMaxAge          time.Duration
+	Http3Enabled        bool
+	H2cEnabled          bool
+	AcmeEnabled         bool
+	Acme                acme.Config
+	AcmeHTTPAddr        string
+	ClientCAPath        string
+	ClientAuth          string
+	ClientCertRules     []middlewares.ClientCertRule
 	ProxyToAddr         string
 	ProxyHeadersEnabled bool
 	ProxyIgnoreRedirect bool
+	ProxyH2c            bool
+
+	// Roots layered below RootDir, a path is served from the first root containing it
+	OverlayDirs []string
+	// File system to serve instead of RootDir, e.g. an embed.FS
+	FS fs.FS
+	// Git repository to serve a tree of instead of RootDir
+	GitDir string
+	// Ref of the tree served by default, other refs can be requested with the "ref" query parameter
+	GitRef string
+
+	// Paths hidden from listings and direct access
+	HideDotfiles bool
+	Exclude      []string
+	IgnoreFiles  bool
+
+	// Units of sizes in directory listings, files.SizeUnitsSI if empty
+	SizeUnits files.SizeUnits
+	// Entries per directory listing page unless ?per_page= is given, 0 lists every entry
+	PageSize int
+
+	// Limits of ?search=, zero values use DefaultSearchDepth, DefaultSearchResults and DefaultSearchTimeout
+	SearchMaxDepth   int
+	SearchMaxResults int
+	SearchTimeout    time.Duration
+
+	// Index text files in the background for ?search=...&content=1
+	TextIndex bool
+	// Files larger than this are not indexed, zero uses fulltext.DefaultMaxFileSize
+	TextIndexMaxSize int64
+	// Time between scans for changed files when root directories cannot be watched, zero uses fulltext.DefaultInterval
+	TextIndexInterval time.Duration
+
+	// How symlinks in root directories are treated, files.SymlinksWithinRoot if empty
+	Symlinks files.SymlinkPolicy
+	// Directories symlinks may lead to with files.SymlinksAllowList
+	SymlinkAllowDirs []string
+
+	// TLS policy, zero values leave the Go defaults
+	TLSMinVersion             uint16
+	TLSMaxVersion             uint16
+	TLSCipherSuites           []uint16
+	TLSCurves                 []tls.CurveID
+	TLSNextProtos             []string
+	TLSSessionTicketsDisabled bool
+	TLSSessionTicketRotation  time.Duration
+
+	// Timeouts and limits, zero values leave the Go defaults
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxConns          int
+	// Time to wait for in-flight requests on shutdown, zero uses DefaultShutdownTimeout
+	ShutdownTimeout time.Duration
+	// On SIGTERM, wait this long for in-flight requests and report the ones cut off. Zero disables draining
+	DrainTimeout time.Duration
+
+	// Wrap the route handler, the first one runs first
+	Middlewares []func(http.Handler) http.Handler
+
+	handler       http.Handler
+	fsys          fs.FS
+	gitRepo       *files.GitRepo
+	filter        *files.Filter
+	textIndex     *fulltext.Index
+	textIndexOnce sync.Once
+	thumbnails    *thumbnail.Cache
+	indexTemplate *custom.Template
+	certStore     *ssl.CertStore
+	connLimiter   *listener.Limiter
+	inFlight      *middlewares.InFlight
+	// Guards listeners and Port, which Start sets while the handler may already serve requests
+	mu        sync.RWMutex
+	listeners []net.Listener
+	servers   []shutdowner
+	serveErrs chan error
+}
+
+// Certificate and private key pair to be presented for a hostname
+type SNICert struct {
+	Host     string
+	CertPath string
+	KeyPath  string
 }
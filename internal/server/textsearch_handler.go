@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/fulltext"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/tmpl/dirview"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+// Lines shown before and after matching lines
+const textSearchContext = 2
+
+// A matching line in JSON full-text search results
+type textMatchJSON struct {
+	// URL path of the file, e.g. "/docs/readme.md"
+	Path   string   `json:"path"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// Last line of JSON full-text search results
+type textSummaryJSON struct {
+	Done      bool `json:"done"`
+	Count     int  `json:"count"`
+	Truncated bool `json:"truncated,omitempty"`
+	// Whether the index was still being built
+	Indexing bool `json:"indexing,omitempty"`
+}
+
+// Handler for directory requests with the "search" and "content" query parameters.
+// Searches the text index for lines under the directory.
+func (c *ServerConfig) textSearchHandler(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, refs *files.GitRefs) {
+	// The index follows the files on disk, not the trees of a git repository
+	if c.textIndex == nil || refs != nil {
+		http.Error(w, "Searching file contents is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	// Handlers served without Start build the index from the first search on
+	c.runTextIndex(context.Background())
+
+	query := r.URL.Query().Get("search")
+	opts := fulltext.SearchOptions{MaxMatches: c.SearchMaxResults, Context: textSearchContext}
+	if opts.MaxMatches == 0 {
+		opts.MaxMatches = DefaultSearchResults
+	}
+
+	results, err := c.textIndex.Search(fsys, name, query, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wantsJSON(r) {
+		c.textSearchJSON(w, results)
+		return
+	}
+
+	nonce, err := generateNonce()
+	if err != nil {
+		http.Error(w, "Cannot generate nonce for CSP", http.StatusInternalServerError)
+		logger.Printf(logger.LogError, "%v\n", err)
+		return
+	}
+
+	setPageHeaders(w, nonce)
+
+	listing := themes.TextSearchListing{
+		Path:     files.RelativeRootFS(name),
+		Query:    query,
+		Results:  results,
+		Indexing: !c.textIndex.Ready(),
+	}
+
+	dirview.RenderTextSearch(w, r, listing, nonce, c.DirViewTheme)
+}
+
+// Writes results as JSON lines, one matching line per line and a summary at the end
+func (c *ServerConfig) textSearchJSON(w http.ResponseWriter, results fulltext.Results) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	encoder := json.NewEncoder(w)
+
+	for _, file := range results.Files {
+		for _, match := range file.Matches {
+			line := textMatchJSON{Path: "/" + file.Path, Line: match.Line, Text: match.Text, Before: match.Before, After: match.After}
+			if err := encoder.Encode(line); err != nil {
+				return
+			}
+		}
+	}
+
+	encoder.Encode(textSummaryJSON{Done: true, Count: results.Count, Truncated: results.Truncated, Indexing: !c.textIndex.Ready()})
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/thumbnail"
+)
+
+// Handler for file requests with the "thumb" query parameter.
+// Serves a scaled down copy of an image, made once and cached. Videos get the one of their poster image.
+func (c *ServerConfig) thumbnailHandler(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, refs *files.GitRefs) {
+	var namespace string
+	if refs != nil {
+		namespace = refs.Current
+	}
+
+	if poster, ok := thumbnail.Poster(fsys, name); ok {
+		name = poster
+	}
+
+	thumb, err := c.thumbnails.Get(fsys, namespace, name)
+	if err != nil {
+		if errors.Is(err, thumbnail.ErrUnsupported) {
+			http.Error(w, "No thumbnail for this file", http.StatusNotFound)
+		} else {
+			http.Error(w, "Cannot make thumbnail", http.StatusInternalServerError)
+			logger.Printf(logger.LogError, "%v\n", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", thumb.Type)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeContent(w, r, name, thumb.ModTime, bytes.NewReader(thumb.Data))
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/logger"
+	"github.com/ducng99/goserve/internal/ssl"
+)
+
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+var DefaultNextProtos = []string{"h2", "http/1.1"}
+
+// Creates the TLS config for the HTTPS server.
+// acmeManager can be nil if ACME is disabled.
+func (c *ServerConfig) newTLSConfig(ctx context.Context, acmeManager *acme.Manager) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate:         c.certStore.GetCertificate,
+		MinVersion:             c.TLSMinVersion,
+		MaxVersion:             c.TLSMaxVersion,
+		CipherSuites:           c.TLSCipherSuites,
+		CurvePreferences:       c.TLSCurves,
+		SessionTicketsDisabled: c.TLSSessionTicketsDisabled,
+		NextProtos:             DefaultNextProtos,
+	}
+
+	if len(c.TLSNextProtos) > 0 {
+		tlsConfig.NextProtos = c.TLSNextProtos
+	}
+
+	if acmeManager != nil {
+		tlsConfig.GetCertificate = acmeManager.GetCertificate
+
+		if acmeManager.Challenge() == acme.ChallengeTLSALPN01 {
+			tlsConfig.NextProtos = append(slices.Clone(tlsConfig.NextProtos), acme.ALPNProto)
+		}
+	}
+
+	if c.TLSSessionTicketRotation > 0 && !c.TLSSessionTicketsDisabled {
+		if err := ssl.RotateSessionTicketKeys(ctx, tlsConfig, c.TLSSessionTicketRotation); err != nil {
+			return nil, fmt.Errorf("cannot generate session ticket keys: %w", err)
+		}
+	}
+
+	if c.ClientCAPath != "" {
+		if err := c.setupClientAuth(tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// Sets up client certificate verification against the CA certificates in ClientCAPath
+func (c *ServerConfig) setupClientAuth(tlsConfig *tls.Config) error {
+	caPEM, err := os.ReadFile(c.ClientCAPath)
+	if err != nil {
+		return fmt.Errorf("cannot read client CA file '%s': %w", c.ClientCAPath, err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in client CA file '%s'", c.ClientCAPath)
+	}
+
+	tlsConfig.ClientCAs = clientCAs
+
+	switch c.ClientAuth {
+	case ClientAuthRequire, "":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return fmt.Errorf("invalid client auth mode '%s'", c.ClientAuth)
+	}
+
+	return nil
+}
+
+// Connections which negotiated TLS parameters have been logged for
+var loggedTLSConns sync.Map
+
+// Logs negotiated TLS parameters once per connection in verbose mode.
+// To be used as [net/http.Server.ConnState]
+func logNegotiatedTLS(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateActive:
+		tlsConn, ok := conn.(*tls.Conn)
+		if !ok {
+			return
+		}
+
+		if _, logged := loggedTLSConns.LoadOrStore(conn, struct{}{}); logged {
+			return
+		}
+
+		connState := tlsConn.ConnectionState()
+		logger.Printf(logger.LogNormal, "%s TLS negotiated: %s, %s, %s, ALPN %q, SNI %q, resumed %t\n",
+			conn.RemoteAddr(),
+			tls.VersionName(connState.Version),
+			tls.CipherSuiteName(connState.CipherSuite),
+			connState.CurveID,
+			connState.NegotiatedProtocol,
+			connState.ServerName,
+			connState.DidResume,
+		)
+	case http.StateClosed, http.StateHijacked:
+		loggedTLSConns.Delete(conn)
+	}
+}
@@ -0,0 +1,85 @@
+package ssl
+
+import (
+	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrNoCertificate = errors.New("no certificate available for the requested server name")
+
+// Holds TLS certificates keyed by hostname, to be selected by SNI.
+// Certificates can be swapped at any time while the server is running.
+type CertStore struct {
+	mu          sync.RWMutex
+	certs       map[string]*tls.Certificate
+	defaultCert *tls.Certificate
+}
+
+func NewCertStore() *CertStore {
+	return &CertStore{
+		certs: make(map[string]*tls.Certificate),
+	}
+}
+
+// Sets the certificate for a hostname, replacing any previous one.
+// Hostname can be a wildcard (e.g. "*.test") to match a single label.
+func (s *CertStore) Set(host string, cert *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.certs[normaliseHost(host)] = cert
+}
+
+// Sets the certificate used when no hostname matches, or when the client does not send SNI
+func (s *CertStore) SetDefault(cert *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaultCert = cert
+}
+
+// Gets the certificate for a hostname, trying an exact match then a wildcard match.
+// Returns nil if no certificate is set for the hostname.
+func (s *CertStore) Lookup(host string) *tls.Certificate {
+	host = normaliseHost(host)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if cert, ok := s.certs[host]; ok {
+		return cert
+	}
+
+	if _, parent, found := strings.Cut(host, "."); found {
+		if cert, ok := s.certs["*."+parent]; ok {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// Selects a certificate for the TLS handshake based on SNI, falling back to the default certificate.
+// To be used as [crypto/tls.Config.GetCertificate]
+func (s *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if hello.ServerName != "" {
+		if cert := s.Lookup(hello.ServerName); cert != nil {
+			return cert, nil
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.defaultCert == nil {
+		return nil, ErrNoCertificate
+	}
+
+	return s.defaultCert, nil
+}
+
+func normaliseHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -19,8 +19,6 @@ import (
 	"os"
 	"path/filepath"
 	"time"
-
-	"github.com/ducng99/goserve/internal/logger"
 )
 
 type KeyPair struct {
@@ -39,8 +37,7 @@ const (
 func NewKeys(validFor time.Duration) (*KeyPair, error) {
 	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		logger.Fatalf("failed to generate private key: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
 	}
 
 	notBefore := time.Now()
@@ -49,8 +46,7 @@ func NewKeys(validFor time.Duration) (*KeyPair, error) {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
-		logger.Fatalf("failed to generate serial number: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
 	}
 
 	template := x509.Certificate{
@@ -68,16 +64,20 @@ func NewKeys(validFor time.Duration) (*KeyPair, error) {
 
 	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privKey.PublicKey, privKey)
 	if err != nil {
-		logger.Fatalf("Failed to create certificate: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
 	}
 
 	// Encode and write certificate and key to bytes.Buffer
 	cert := bytes.NewBuffer([]byte{})
 	pem.Encode(cert, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
 
+	keyBlock, err := pemBlockForKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+
 	key := bytes.NewBuffer([]byte{})
-	pem.Encode(key, pemBlockForKey(privKey))
+	pem.Encode(key, keyBlock)
 
 	fingerprint := sha256.Sum256(derBytes)
 
@@ -90,13 +90,12 @@ func NewKeys(validFor time.Duration) (*KeyPair, error) {
 	return keyPair, nil
 }
 
-func pemBlockForKey(key *ecdsa.PrivateKey) *pem.Block {
+func pemBlockForKey(key *ecdsa.PrivateKey) (*pem.Block, error) {
 	b, err := x509.MarshalECPrivateKey(key)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to marshal ECDSA private key: %v", err)
-		os.Exit(2)
+		return nil, fmt.Errorf("unable to marshal ECDSA private key: %w", err)
 	}
-	return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}
+	return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}, nil
 }
 
 // Saves certificate and private key to the given directory.
@@ -0,0 +1,127 @@
+package ssl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Number of previous session ticket keys kept after a rotation, so recently issued tickets can still be resumed
+const previousTicketKeys = 2
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var curves = map[string]tls.CurveID{
+	"x25519":         tls.X25519,
+	"x25519mlkem768": tls.X25519MLKEM768,
+	"p256":           tls.CurveP256,
+	"p-256":          tls.CurveP256,
+	"curvep256":      tls.CurveP256,
+	"p384":           tls.CurveP384,
+	"p-384":          tls.CurveP384,
+	"curvep384":      tls.CurveP384,
+	"p521":           tls.CurveP521,
+	"p-521":          tls.CurveP521,
+	"curvep521":      tls.CurveP521,
+}
+
+// Parses a TLS version such as "1.2" or "1.3".
+// An empty string returns 0, leaving the Go default.
+func ParseVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+
+	id, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(version), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version '%s'", version)
+	}
+
+	return id, nil
+}
+
+// Parses cipher suite names as returned by [crypto/tls.CipherSuiteName], e.g. "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite '%s'", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Parses key exchange curve names, e.g. "X25519", "P256" or "X25519MLKEM768"
+func ParseCurves(names []string) ([]tls.CurveID, error) {
+	ids := make([]tls.CurveID, 0, len(names))
+	for _, name := range names {
+		id, ok := curves[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown curve '%s'", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Replaces the session ticket key of the TLS config every interval until ctx is done.
+// The config must be used directly by the listener, as clones do not receive new keys.
+func RotateSessionTicketKeys(ctx context.Context, tlsConfig *tls.Config, interval time.Duration) error {
+	keys := make([][32]byte, 0, previousTicketKeys+1)
+
+	rotate := func() error {
+		var key [32]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+
+		// Newest key is used for new tickets, older ones only for resumption
+		keys = append([][32]byte{key}, keys...)
+		if len(keys) > previousTicketKeys+1 {
+			keys = keys[:previousTicketKeys+1]
+		}
+
+		tlsConfig.SetSessionTicketKeys(keys)
+
+		return nil
+	}
+
+	if err := rotate(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rotate()
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,266 @@
+// Thumbnails of images, scaled down on the server and kept in memory
+package thumbnail
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	// Longest side of thumbnails in pixels
+	Size = 320
+	// Bytes of thumbnails kept in memory
+	DefaultCacheSize = 64 << 20
+	// Largest image decoded, in pixels, so small files cannot expand to huge images
+	maxPixels = 50_000_000
+	// Largest image file read, checked before reading it
+	maxFileSize = 32 << 20
+	jpegQuality = 80
+)
+
+var ErrUnsupported = errors.New("unsupported image")
+
+// Checks whether a thumbnail can be made of a file, by its extension
+func Supported(name string) bool {
+	_, ok := decoders[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+type decoder struct {
+	decode       func(r io.Reader) (image.Image, error)
+	decodeConfig func(r io.Reader) (image.Config, error)
+}
+
+var decoders = map[string]decoder{
+	".jpg":  {jpeg.Decode, jpeg.DecodeConfig},
+	".jpeg": {jpeg.Decode, jpeg.DecodeConfig},
+	".png":  {png.Decode, png.DecodeConfig},
+	// Only the first frame of animations
+	".gif":  {gif.Decode, gif.DecodeConfig},
+	".webp": {webp.Decode, webp.DecodeConfig},
+}
+
+// Image extensions looked for next to a video, in order
+var posterExts = []string{".jpg", ".jpeg", ".png", ".webp", ".gif"}
+
+// Finds the image next to a video to use as its poster, e.g. clip.jpg for clip.mp4
+func Poster(fsys fs.FS, name string) (string, bool) {
+	if Supported(name) {
+		return "", false
+	}
+
+	base := strings.TrimSuffix(name, path.Ext(name))
+	for _, ext := range posterExts {
+		if info, err := fs.Stat(fsys, base+ext); err == nil && info.Mode().IsRegular() {
+			return base + ext, true
+		}
+	}
+
+	return "", false
+}
+
+// A thumbnail, JPEG for opaque images and PNG for transparent ones
+type Thumbnail struct {
+	Data []byte
+	Type string
+	// Modification time of the source image
+	ModTime time.Time
+}
+
+// Cache of thumbnails, dropping the least recently used ones beyond its size
+type Cache struct {
+	maxBytes int64
+	// Limits thumbnails made at once, as decoding is heavy on CPU and memory
+	making chan struct{}
+
+	mu      sync.Mutex
+	used    int64
+	lru     *list.List
+	entries map[cacheKey]*list.Element
+}
+
+// Source images are identified by their path, and changed ones by their size and modification time
+type cacheKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+type cacheEntry struct {
+	key       cacheKey
+	thumbnail *Thumbnail
+}
+
+func NewCache(maxBytes int64) *Cache {
+	if maxBytes == 0 {
+		maxBytes = DefaultCacheSize
+	}
+
+	return &Cache{
+		maxBytes: maxBytes,
+		making:   make(chan struct{}, runtime.NumCPU()),
+		lru:      list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+	}
+}
+
+// Gets the thumbnail of an image, making it if it is not cached.
+// The namespace tells apart files of different trees with the same name, e.g. git refs.
+func (c *Cache) Get(fsys fs.FS, namespace, name string) (*Thumbnail, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cacheKey{name: namespace + "\x00" + name, size: info.Size(), modTime: info.ModTime()}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*cacheEntry).thumbnail, nil
+	}
+	c.mu.Unlock()
+
+	c.making <- struct{}{}
+	thumbnail, err := Make(fsys, name)
+	<-c.making
+	if err != nil {
+		return nil, err
+	}
+	thumbnail.ModTime = info.ModTime()
+
+	c.add(key, thumbnail)
+
+	return thumbnail, nil
+}
+
+func (c *Cache) add(key cacheKey, thumbnail *Thumbnail) {
+	size := int64(len(thumbnail.Data))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Made by another request meanwhile
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, thumbnail: thumbnail})
+	c.used += size
+
+	for c.used > c.maxBytes {
+		oldest := c.lru.Back()
+		entry := oldest.Value.(*cacheEntry)
+
+		c.lru.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.used -= int64(len(entry.thumbnail.Data))
+	}
+}
+
+// Makes the thumbnail of an image, fitting it in [Size] pixels. Smaller images are kept at their size
+func Make(fsys fs.FS, name string) (*Thumbnail, error) {
+	dec, ok := decoders[strings.ToLower(path.Ext(name))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("%w: %d bytes is too large", ErrUnsupported, info.Size())
+	}
+
+	// The header read for the config is replayed to decode the image, without reading the file twice
+	var header bytes.Buffer
+	config, err := dec.decodeConfig(io.TeeReader(file, &header))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is too large", ErrUnsupported, config.Width, config.Height)
+	}
+
+	src, err := dec.decode(io.MultiReader(&header, io.LimitReader(file, maxFileSize-int64(header.Len()))))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	dst := scale(src)
+
+	var buf bytes.Buffer
+	thumbnail := &Thumbnail{}
+	if opaque(dst) {
+		thumbnail.Type = "image/jpeg"
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		thumbnail.Type = "image/png"
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail.Data = buf.Bytes()
+	return thumbnail, nil
+}
+
+// Scales an image down to fit in [Size] pixels, keeping its aspect ratio
+func scale(src image.Image) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width <= Size && height <= Size {
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	if width >= height {
+		width, height = Size, max(height*Size/width, 1)
+	} else {
+		width, height = max(width*Size/height, 1), Size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	return dst
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+
+	return false
+}
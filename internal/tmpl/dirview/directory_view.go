@@ -5,26 +5,59 @@ import (
 	"net/http"
 
 	"github.com/a-h/templ"
-	"github.com/ducng99/goserve/internal/files"
 	"github.com/ducng99/goserve/internal/logger"
 	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
 	"github.com/ducng99/goserve/internal/tmpl/dirview/themes/basic"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes/gallery"
 	"github.com/ducng99/goserve/internal/tmpl/dirview/themes/pretty"
 )
 
-func Render(w http.ResponseWriter, r *http.Request, relativePath string, entries []files.DirEntry, nonce string, theme string) {
-	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+func Render(w http.ResponseWriter, r *http.Request, listing themes.Listing, nonce string, theme string) {
+	switch theme {
+	case themes.ThemeGallery:
+		render(w, r, gallery.View(listing), nonce)
+	case themes.ThemePretty:
+		render(w, r, pretty.View(listing), nonce)
+	default:
+		render(w, r, basic.View(listing), nonce)
+	}
+}
 
-	var templComp templ.Component
-	ctx := context.WithValue(r.Context(), "nonce", nonce)
+// Renders the results of a search while they are found.
+// The gallery theme only has its own listing, other pages use the pretty theme.
+func RenderSearch(w http.ResponseWriter, r *http.Request, listing themes.SearchListing, nonce string, theme string) {
+	switch theme {
+	case themes.ThemePretty, themes.ThemeGallery:
+		render(w, r, pretty.Search(listing), nonce)
+	default:
+		render(w, r, basic.Search(listing), nonce)
+	}
+}
 
+// Renders the lines matching a full-text search
+func RenderTextSearch(w http.ResponseWriter, r *http.Request, listing themes.TextSearchListing, nonce string, theme string) {
 	switch theme {
-	case themes.ThemePretty:
-		files.Sort(entries)
-		templComp = pretty.View(relativePath, entries)
+	case themes.ThemePretty, themes.ThemeGallery:
+		render(w, r, pretty.TextSearch(listing), nonce)
+	default:
+		render(w, r, basic.TextSearch(listing), nonce)
+	}
+}
+
+// Renders the preview of a file
+func RenderPreview(w http.ResponseWriter, r *http.Request, p themes.Preview, nonce string, theme string) {
+	switch theme {
+	case themes.ThemePretty, themes.ThemeGallery:
+		render(w, r, pretty.Preview(p), nonce)
 	default:
-		templComp = basic.View(relativePath, entries)
+		render(w, r, basic.Preview(p), nonce)
 	}
+}
+
+func render(w http.ResponseWriter, r *http.Request, templComp templ.Component, nonce string) {
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+
+	ctx := context.WithValue(r.Context(), "nonce", nonce)
 
 	if err := templComp.Render(ctx, w); err != nil {
 		http.Error(w, "Cannot render indexing page", http.StatusInternalServerError)
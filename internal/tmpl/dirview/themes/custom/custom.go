@@ -0,0 +1,153 @@
+// Directory listings rendered by user templates written with html/template.
+//
+// A template is either a single file, or a directory with an "index.html" template.
+// Other ".html" files of the directory can be used as templates with {{template "name.html" .}},
+// and the rest are served as static assets, linked with {{asset "style.css"}}.
+// Templates are executed with [Data].
+package custom
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ducng99/goserve/internal/server/assets"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+// Template executed for listings in a theme directory
+const IndexName = "index.html"
+
+var ErrInvalidTemplate = errors.New("invalid index template")
+
+// A loaded listing template and its assets
+type Template struct {
+	tmpl *template.Template
+	// URLs of the assets by their path in the theme directory
+	assets map[string]string
+}
+
+// Loads a template file, or a theme directory with an [IndexName] template and assets
+func Load(name string) (*Template, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	t := &Template{assets: make(map[string]string)}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+
+		t.tmpl, err = template.New(filepath.Base(name)).Funcs(t.funcs()).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+
+		return t, nil
+	}
+
+	themeFS := os.DirFS(name)
+	if _, err := fs.Stat(themeFS, IndexName); err != nil {
+		return nil, fmt.Errorf("%w: %s has no %s", ErrInvalidTemplate, name, IndexName)
+	}
+
+	if err := t.addAssets(themeFS); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	t.tmpl, err = template.New(IndexName).Funcs(t.funcs()).ParseFS(themeFS, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	return t, nil
+}
+
+// Registers the files of a theme directory which are not templates as assets.
+// They are served under a prefix made of their hash, keeping their paths so relative URLs between them work,
+// e.g. url(../fonts/brand.woff2) in css/site.css.
+func (t *Template) addAssets(themeFS fs.FS) error {
+	var themeAssets []assets.Asset
+	var names []string
+
+	err := fs.WalkDir(themeFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || (path.Dir(name) == "." && path.Ext(name) == ".html") {
+			return nil
+		}
+
+		content, err := fs.ReadFile(themeFS, name)
+		if err != nil {
+			return err
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(content)
+		}
+
+		themeAssets = append(themeAssets, assets.Asset{Name: path.Base(name), Type: contentType, Content: content})
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The same theme gets the same URLs when loaded again
+	hash := sha256.New()
+	for i, asset := range themeAssets {
+		fmt.Fprintf(hash, "%s\x00%s\x00%d\x00", names[i], asset.Type, len(asset.Content))
+		hash.Write(asset.Content)
+	}
+	prefix := hex.EncodeToString(hash.Sum(nil)[:8])
+
+	for i, asset := range themeAssets {
+		t.assets[names[i]] = asset.AddAssetAt(prefix + "/" + names[i])
+	}
+
+	return nil
+}
+
+func (t *Template) funcs() template.FuncMap {
+	return template.FuncMap{
+		// Gets the URL of an asset of the theme directory
+		"asset": func(name string) (string, error) {
+			assetPath, ok := t.assets[strings.TrimPrefix(name, "/")]
+			if !ok {
+				return "", fmt.Errorf("no asset named '%s'", name)
+			}
+
+			return assetPath, nil
+		},
+	}
+}
+
+// Renders a listing, returning an error without writing anything if the template fails
+func (t *Template) Render(w http.ResponseWriter, listing themes.Listing, nonce string) error {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, NewData(listing, nonce)); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,173 @@
+package custom
+
+import (
+	"html/template"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+// Data of a listing page, given to templates as "."
+type Data struct {
+	// Path of the directory from the root, e.g. "/docs/api"
+	Path string
+	// Links to the root and each directory down to this one
+	Breadcrumbs []Breadcrumb
+	// URL of the parent directory, empty at the root
+	ParentURL string
+	// Entries of the page, after sorting and filtering
+	Entries []Entry
+	// Position of the page and links to the others, nil if the listing is not split into pages
+	Page *Page
+	// Key the entries are sorted by: name, type, mtime, size or none
+	Sort string
+	// Whether the entries are sorted in descending order
+	Desc bool
+	// Readme of the directory, nil if it has none
+	Readme *Readme
+	// Git ref being browsed, empty when not serving a git repository
+	Ref string
+	// Nonce allowed by the Content-Security-Policy, needed on <style>, <script> and <link> tags
+	Nonce string
+
+	listing themes.Listing
+}
+
+// A directory in the breadcrumbs
+type Breadcrumb struct {
+	// Name of the directory, "/" for the root
+	Name string
+	URL  string
+}
+
+// A file or directory of the listing
+type Entry struct {
+	// Name of the entry, directories end with "/"
+	Name string
+	URL  string
+	Dir  bool
+	// Size in bytes, 0 for directories
+	Size int64
+	// Size for display, e.g. "1.2 MB", or the number of items of a directory, e.g. "3 items"
+	SizeText string
+	ModTime  time.Time
+	// Time since the last modification, e.g. "5 minutes ago"
+	ModTimeText string
+	// MIME type by extension, empty for directories
+	MIMEType string
+	// Permissions in "ls -l" form, e.g. "-rw-r--r--"
+	Permissions string
+	// Owner and group, empty if the file system has none
+	Owner string
+	Group string
+	// Target of a symlink, empty for other entries
+	LinkTarget string
+	// Whether the entry can be opened, false for symlinks which are not followed
+	Followable bool
+}
+
+// Position of a listing split into pages
+type Page struct {
+	// Number of the page from 1, 0 for a page after a cursor
+	Number int
+	// Links to other pages, empty if there is none
+	FirstURL string
+	PrevURL  string
+	NextURL  string
+}
+
+// Rendered readme of a directory
+type Readme struct {
+	// Name of the file, e.g. "README.md"
+	Name string
+	HTML template.HTML
+}
+
+// Gets the data of a listing for templates. Streamed listings are read whole.
+func NewData(listing themes.Listing, nonce string) Data {
+	data := Data{
+		Path:        listing.Path,
+		Breadcrumbs: breadcrumbs(listing),
+		ParentURL:   listing.ParentURL(),
+		Sort:        string(listing.Options.Sort),
+		Desc:        listing.Options.Desc,
+		Nonce:       nonce,
+		listing:     listing,
+	}
+
+	for _, entry := range listing.Rows() {
+		data.Entries = append(data.Entries, newEntry(listing, entry))
+	}
+
+	if listing.Paginated() {
+		data.Page = &Page{
+			Number:   listing.Page.Number,
+			FirstURL: listing.FirstURL(),
+			PrevURL:  listing.PrevURL(),
+			NextURL:  listing.NextURL(),
+		}
+	}
+
+	if listing.Readme != nil {
+		// Rendered with the safe Markdown renderer
+		data.Readme = &Readme{Name: listing.Readme.Name, HTML: template.HTML(listing.Readme.HTML)}
+	}
+
+	if listing.Refs != nil {
+		data.Ref = listing.Refs.Current
+	}
+
+	return data
+}
+
+// Gets the URL sorting by key, in the reverse order if the listing is already sorted by it.
+// Used as {{.SortURL "mtime"}}
+func (d Data) SortURL(key string) string {
+	return d.listing.SortURL(key)
+}
+
+func newEntry(listing themes.Listing, entry files.DirEntry) Entry {
+	e := Entry{
+		Name:        entry.Name(true),
+		URL:         listing.EntryURL(entry),
+		Dir:         entry.IsDir(),
+		ModTime:     entry.ModTime(),
+		ModTimeText: entry.ModTimeRelative(),
+		MIMEType:    entry.MIMEType(),
+		Permissions: entry.Permissions(),
+		Owner:       entry.Owner(),
+		Group:       entry.Group(),
+		LinkTarget:  entry.LinkTarget(),
+		Followable:  entry.Followable(),
+	}
+
+	if e.Dir {
+		e.SizeText = entry.Items()
+	} else {
+		e.SizeText = entry.SizeIn(listing.Units)
+		if info, err := entry.Info(); err == nil {
+			e.Size = info.Size()
+		}
+	}
+
+	return e
+}
+
+func breadcrumbs(listing themes.Listing) []Breadcrumb {
+	crumbs := []Breadcrumb{{Name: "/", URL: "/" + listing.Refs.Query()}}
+
+	dirURL := "/"
+	for _, name := range strings.Split(strings.Trim(listing.Path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+
+		dirURL = path.Join(dirURL, name) + "/"
+		crumbs = append(crumbs, Breadcrumb{Name: name, URL: dirURL + listing.Refs.Query()})
+	}
+
+	return crumbs
+}
@@ -0,0 +1,49 @@
+package themes
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/preview"
+	"github.com/ducng99/goserve/internal/thumbnail"
+)
+
+// Gets whether an entry is shown as an image or a video in galleries, empty for other entries
+func MediaKind(entry files.DirEntry) preview.Kind {
+	switch mimeType := entry.MIMEType(); {
+	case strings.HasPrefix(mimeType, "image/"):
+		return preview.KindImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return preview.KindVideo
+	default:
+		return ""
+	}
+}
+
+// Gets the URL of an entry itself, even when previewing
+func (l Listing) RawURL(entry files.DirEntry) string {
+	return path.Join(l.Path, entry.Name(false)) + l.Refs.Query()
+}
+
+// Gets the URL of the thumbnail of a video's poster image, e.g. clip.jpg next to clip.mp4
+func (l Listing) PosterURL(entry files.DirEntry) string {
+	if query := l.Refs.Query(); query != "" {
+		return l.RawURL(entry) + "&thumb=1"
+	}
+
+	return l.RawURL(entry) + "?thumb=1"
+}
+
+// Gets the URL of the thumbnail of an image. Images without server thumbnails, e.g. SVG, are shown as they are
+func (l Listing) ThumbnailURL(entry files.DirEntry) string {
+	if !thumbnail.Supported(entry.Name(false)) {
+		return l.RawURL(entry)
+	}
+
+	if query := l.Refs.Query(); query != "" {
+		return l.RawURL(entry) + "&thumb=1"
+	}
+
+	return l.RawURL(entry) + "?thumb=1"
+}
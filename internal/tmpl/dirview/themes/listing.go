@@ -0,0 +1,246 @@
+package themes
+
+import (
+	"iter"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/preview"
+)
+
+// Data of a directory listing page, rendered by the themes
+type Listing struct {
+	// Path of the directory from the root, e.g. "/docs"
+	Path string
+	// Entries after filtering and sorting, or the first entries when streamed
+	Entries []files.DirEntry
+	// Entries following Entries when the directory is streamed (see [files.EntryStream]), nil otherwise
+	Stream iter.Seq[files.DirEntry]
+	// Position of the page, complete once all rows are rendered
+	Page *files.Page
+	// Refs of the git repository being browsed, nil when not serving a git tree
+	Refs *files.GitRefs
+	// Sorting and filtering applied to the entries
+	Options files.ListOptions
+	// Query parameters of the request, kept in sorting links
+	Query url.Values
+	// Units of entry sizes
+	Units files.SizeUnits
+	// Whether file contents can be searched
+	TextIndex bool
+	// Readme of the directory shown below the entries, nil if it has none
+	Readme *preview.Readme
+}
+
+// Optional columns of a listing, shown if any entry has a value for them
+type Columns struct {
+	// Roots the entries come from, when serving multiple roots
+	Layer bool
+	// Last commits, when serving a git tree
+	Commit bool
+	// Owners, when the file system records them
+	Owner bool
+}
+
+// Gets the optional columns to show. Streamed listings only look at the first entries
+func (l Listing) Columns() Columns {
+	return Columns{
+		Layer:  files.HasLayers(l.Entries),
+		Commit: files.HasCommits(l.Entries),
+		Owner:  files.HasOwners(l.Entries),
+	}
+}
+
+// Gets the empty search form
+func (l Listing) SearchForm() SearchForm {
+	return SearchForm{TextIndex: l.TextIndex, Refs: l.Refs}
+}
+
+// Gets the URL of an entry, directories end with "/".
+// When previewing, files link to their preview and directories keep previewing.
+func (l Listing) EntryURL(entry files.DirEntry) string {
+	entryURL := path.Join(l.Path, entry.Name(false))
+	if entry.IsDir() {
+		entryURL += "/"
+	}
+
+	if l.Previewing() {
+		return withView(entryURL, l.Refs)
+	}
+
+	return entryURL + l.Refs.Query()
+}
+
+// Checks whether files are opened in previews, toggled with ?view=1
+func (l Listing) Previewing() bool {
+	return l.Query.Get("view") != ""
+}
+
+// Gets the URL of the listing with previews toggled
+func (l Listing) PreviewToggleURL() string {
+	query := l.copyQuery()
+	if l.Previewing() {
+		query.Del("view")
+	} else {
+		query.Set("view", "1")
+	}
+
+	return "?" + query.Encode()
+}
+
+// Gets the URL of the parent directory, empty at the root
+func (l Listing) ParentURL() string {
+	if l.Path == "/" {
+		return ""
+	}
+
+	if l.Previewing() {
+		return withView(path.Dir(l.Path), l.Refs)
+	}
+
+	return path.Dir(l.Path) + l.Refs.Query()
+}
+
+// Gets the URL sorting by key, in the reverse order if the listing is already sorted by it
+func (l Listing) SortURL(key string) string {
+	query := l.copyQuery()
+
+	// The position in the previous order is meaningless
+	query.Del("page")
+	query.Del("cursor")
+
+	query.Set("sort", key)
+	if files.SortKey(key) == l.Options.Sort && !l.Options.Desc {
+		query.Set("order", "desc")
+	} else {
+		query.Del("order")
+	}
+
+	return "?" + query.Encode()
+}
+
+// Gets an arrow showing the order if the listing is sorted by key, otherwise an empty string
+func (l Listing) SortIndicator(key string) string {
+	if files.SortKey(key) != l.Options.Sort {
+		return ""
+	}
+
+	if l.Options.Desc {
+		return "▼"
+	}
+
+	return "▲"
+}
+
+// Rows rendered before the page is flushed to the client
+const FlushRows = 256
+
+// Gets all entries of the page in chunks of [FlushRows], reading the stream if there is one
+func (l Listing) RowChunks() iter.Seq[[]files.DirEntry] {
+	return func(yield func([]files.DirEntry) bool) {
+		chunk := make([]files.DirEntry, 0, FlushRows)
+		for _, entry := range l.Rows() {
+			chunk = append(chunk, entry)
+			if len(chunk) == FlushRows {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]files.DirEntry, 0, FlushRows)
+			}
+		}
+
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Gets all entries of the page with their index, reading the stream if there is one
+func (l Listing) Rows() iter.Seq2[int, files.DirEntry] {
+	return func(yield func(int, files.DirEntry) bool) {
+		for i, entry := range l.Entries {
+			if !yield(i, entry) {
+				return
+			}
+		}
+
+		if l.Stream == nil {
+			return
+		}
+
+		i := len(l.Entries)
+		for entry := range l.Stream {
+			if !yield(i, entry) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Checks whether the listing is split into pages
+func (l Listing) Paginated() bool {
+	return l.Page != nil && l.Page.PerPage > 0
+}
+
+// Gets a label of the page, e.g. "Page 2". Empty for a page after a cursor
+func (l Listing) PageLabel() string {
+	if l.Page == nil || l.Page.Number == 0 {
+		return ""
+	}
+
+	return "Page " + strconv.Itoa(l.Page.Number)
+}
+
+// Gets the URL of the first page, empty if this is the first page
+func (l Listing) FirstURL() string {
+	if l.Page == nil || l.Page.Number == 1 {
+		return ""
+	}
+
+	query := l.copyQuery()
+	query.Del("page")
+	query.Del("cursor")
+
+	return "?" + query.Encode()
+}
+
+// Gets the URL of the previous page, empty on the first page or after a cursor
+func (l Listing) PrevURL() string {
+	if l.Page == nil || l.Page.Number <= 1 {
+		return ""
+	}
+
+	query := l.copyQuery()
+	query.Set("page", strconv.Itoa(l.Page.Number-1))
+
+	return "?" + query.Encode()
+}
+
+// Gets the URL of the next page, empty on the last page.
+// Pages after a cursor link to the next one with a cursor, otherwise with the page number.
+func (l Listing) NextURL() string {
+	if l.Page == nil || !l.Page.HasNext {
+		return ""
+	}
+
+	query := l.copyQuery()
+	if l.Page.Number == 0 {
+		query.Set("cursor", l.Page.NextCursor)
+	} else {
+		query.Set("page", strconv.Itoa(l.Page.Number+1))
+	}
+
+	return "?" + query.Encode()
+}
+
+func (l Listing) copyQuery() url.Values {
+	query := url.Values{}
+	for name, values := range l.Query {
+		query[name] = values
+	}
+
+	return query
+}
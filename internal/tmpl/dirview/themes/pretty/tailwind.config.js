@@ -5,7 +5,13 @@ module.exports = {
     theme: {
         extend: {
             gridTemplateColumns: {
-                '4-1-1': '4fr 1fr 1fr',
+                // Name, type, modified, size, permissions, then optional columns
+                'listing': '4fr 2fr 2fr 1fr 1fr',
+                'listing-owner': '4fr 2fr 2fr 1fr 1fr 2fr',
+                'listing-commit': '4fr 2fr 4fr 2fr 1fr 1fr',
+                'listing-commit-owner': '4fr 2fr 4fr 2fr 1fr 1fr 2fr',
+                // Path, modified, size
+                'search': '6fr 2fr 1fr',
             },
         },
     },
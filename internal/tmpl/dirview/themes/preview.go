@@ -0,0 +1,65 @@
+package themes
+
+import (
+	"path"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/preview"
+	"github.com/ducng99/goserve/internal/server/assets"
+)
+
+// Data of a file preview page, rendered by the themes
+type Preview struct {
+	// Path of the file from the root, e.g. "/docs/readme.md"
+	Path string
+	Kind preview.Kind
+	// Rendered Markdown or highlighted code, empty for media and files too large to render
+	HTML string
+	// Whether the file is too large to be rendered
+	TooLarge bool
+	Entry    files.DirEntry
+	// Refs of the git repository being browsed, nil when not serving a git tree
+	Refs *files.GitRefs
+	// Units of the file size
+	Units files.SizeUnits
+}
+
+// Gets the name of the file
+func (p Preview) Name() string {
+	return path.Base(p.Path)
+}
+
+// Gets the URL of the file itself
+func (p Preview) RawURL() string {
+	return p.Path + p.Refs.Query()
+}
+
+// Gets the URL of the directory containing the file, which keeps opening previews
+func (p Preview) DirURL() string {
+	dirURL := path.Dir(p.Path)
+	if dirURL != "/" {
+		dirURL += "/"
+	}
+
+	return withView(dirURL, p.Refs)
+}
+
+var highlightCSSPath, _ = assets.Asset{Name: "highlight.css", Type: "text/css", Content: []byte(preview.HighlightCSS())}.AddAsset()
+
+// Gets the URL of the style sheet of highlighted code, empty for other kinds
+func (p Preview) CSSURL() string {
+	if p.Kind != preview.KindCode || p.HTML == "" {
+		return ""
+	}
+
+	return highlightCSSPath
+}
+
+// Appends view=1 to a URL with the query of refs
+func withView(url string, refs *files.GitRefs) string {
+	if query := refs.Query(); query != "" {
+		return url + query + "&view=1"
+	}
+
+	return url + "?view=1"
+}
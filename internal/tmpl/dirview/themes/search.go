@@ -0,0 +1,89 @@
+package themes
+
+import (
+	"fmt"
+	"iter"
+	"path"
+
+	"github.com/ducng99/goserve/internal/files"
+)
+
+// Data of a search results page, rendered by the themes
+type SearchListing struct {
+	// Path of the searched directory from the root, e.g. "/docs"
+	Path string
+	// The search, its count and stop reason are complete once all results are rendered
+	Search *files.Search
+	// Matches yielded as they are found
+	Results iter.Seq[files.SearchResult]
+	// Refs of the git repository being browsed, nil when not serving a git tree
+	Refs *files.GitRefs
+	// Units of entry sizes
+	Units files.SizeUnits
+	// Whether file contents can be searched
+	TextIndex bool
+}
+
+// Fields of the search form
+type SearchForm struct {
+	Query string
+	// Whether file contents are searched instead of names
+	Content bool
+	// Whether the option to search file contents is shown
+	TextIndex bool
+	// Refs of the git repository being browsed, the current ref is kept in searches
+	Refs *files.GitRefs
+}
+
+// Gets the URL of a result, directories end with "/"
+func (l SearchListing) ResultURL(result files.SearchResult) string {
+	resultURL := path.Join(l.Path, result.Path)
+	if result.Entry.IsDir() {
+		resultURL += "/"
+	}
+
+	return resultURL + l.Refs.Query()
+}
+
+// Gets the URL of the searched directory
+func (l SearchListing) DirURL() string {
+	dirURL := l.Path
+	if dirURL != "/" {
+		dirURL += "/"
+	}
+
+	return dirURL + l.Refs.Query()
+}
+
+// Gets the current ref when browsing a git tree, to keep it in the search form
+func (l SearchListing) Ref() string {
+	if l.Refs == nil {
+		return ""
+	}
+
+	return l.Refs.Current
+}
+
+// Gets the search form, filled with the query
+func (l SearchListing) SearchForm() SearchForm {
+	return SearchForm{Query: l.Search.Query, TextIndex: l.TextIndex, Refs: l.Refs}
+}
+
+// Describes the results once they are all rendered, e.g. "12 results"
+func (l SearchListing) Summary() string {
+	count := fmt.Sprintf("%d results", l.Search.Count)
+	if l.Search.Count == 1 {
+		count = "1 result"
+	}
+
+	switch l.Search.Stopped {
+	case files.SearchStopResults:
+		return fmt.Sprintf("Showing the first %s, refine the search to see more", count)
+	case files.SearchStopTime:
+		return fmt.Sprintf("%s, the search took too long and was stopped", count)
+	case files.SearchStopDepth:
+		return fmt.Sprintf("%s, deeper directories were not searched", count)
+	default:
+		return count
+	}
+}
@@ -0,0 +1,107 @@
+package themes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ducng99/goserve/internal/fulltext"
+)
+
+// Data of a page of lines matching a full-text search, rendered by the themes
+type TextSearchListing struct {
+	// Path of the searched directory from the root, e.g. "/docs"
+	Path  string
+	Query string
+	// Matching lines, grouped by file
+	Results fulltext.Results
+	// Whether the index is still being built, so some files may be missing
+	Indexing bool
+}
+
+// Gets the path of a file from the searched directory, e.g. "api/readme.txt"
+func (l TextSearchListing) FilePath(file fulltext.FileMatches) string {
+	return strings.TrimPrefix("/"+file.Path, strings.TrimSuffix(l.Path, "/")+"/")
+}
+
+// Gets the URL of a file
+func (l TextSearchListing) FileURL(file fulltext.FileMatches) string {
+	return "/" + file.Path
+}
+
+// Gets the URL of the preview of a file at the given line
+func (l TextSearchListing) LineURL(file fulltext.FileMatches, line int) string {
+	return withView(l.FileURL(file), nil) + "#L" + strconv.Itoa(line)
+}
+
+// Gets the URL of the searched directory
+func (l TextSearchListing) DirURL() string {
+	if l.Path == "/" {
+		return l.Path
+	}
+
+	return l.Path + "/"
+}
+
+// Gets the search form, with the content option checked
+func (l TextSearchListing) SearchForm() SearchForm {
+	return SearchForm{Query: l.Query, Content: true, TextIndex: true}
+}
+
+// Describes the results, e.g. "12 matching lines in 3 files"
+func (l TextSearchListing) Summary() string {
+	lines := fmt.Sprintf("%d matching lines", l.Results.Count)
+	if l.Results.Count == 1 {
+		lines = "1 matching line"
+	}
+
+	filesCount := fmt.Sprintf("%d files", len(l.Results.Files))
+	if len(l.Results.Files) == 1 {
+		filesCount = "1 file"
+	}
+
+	summary := lines + " in " + filesCount
+	if l.Results.Truncated {
+		summary = "Showing the first " + summary + ", refine the search to see more"
+	}
+
+	return summary
+}
+
+// Part of a line, either matching the query or not
+type TextPart struct {
+	Text  string
+	Match bool
+}
+
+// Splits a line around the matches of the query, ignoring case
+func (l TextSearchListing) Highlight(line string) []TextPart {
+	lower := strings.ToLower(line)
+	needle := strings.ToLower(l.Query)
+
+	// Offsets in the lowercased line only hold when lowercasing kept the length
+	if needle == "" || len(lower) != len(line) {
+		return []TextPart{{Text: line}}
+	}
+
+	var parts []TextPart
+	for {
+		i := strings.Index(lower, needle)
+		if i == -1 {
+			break
+		}
+
+		if i > 0 {
+			parts = append(parts, TextPart{Text: line[:i]})
+		}
+		parts = append(parts, TextPart{Text: line[i : i+len(needle)], Match: true})
+
+		line, lower = line[i+len(needle):], lower[i+len(needle):]
+	}
+
+	if line != "" {
+		parts = append(parts, TextPart{Text: line})
+	}
+
+	return parts
+}
@@ -3,12 +3,14 @@ package themes
 const (
 	ThemeBasic  = "basic"
 	ThemePretty = "pretty"
+	// Grid of thumbnails, for folders of images and videos
+	ThemeGallery = "gallery"
 )
 
 // Checks whether the input theme exists
 func Exists(inputTheme string) bool {
 	switch inputTheme {
-	case ThemePretty, ThemeBasic:
+	case ThemePretty, ThemeBasic, ThemeGallery:
 		return true
 	default:
 		return false
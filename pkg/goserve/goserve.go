@@ -0,0 +1,250 @@
+// Package goserve embeds the goserve static file server in other Go programs.
+//
+// A [Server] serves a directory, an archive or any [io/fs.FS] with index pages, or proxies to another server,
+// with the same features as the goserve command, configured with [Option] values:
+//
+//	srv, err := goserve.New(
+//		goserve.WithRootDir("./public"),
+//		goserve.WithAddr("127.0.0.1:0"),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//
+//	if err := srv.Start(ctx); err != nil {
+//		return err
+//	}
+//	defer srv.Shutdown(context.Background())
+//
+// Use [Server.Handler] to mount goserve in an existing mux instead of starting its own listeners.
+// Listings link to absolute paths, so the handler must be mounted at "/", e.g. with a host pattern:
+//
+//	mux.Handle("files.example.com/", srv.Handler())
+package goserve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/listener"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/server/middlewares"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+const (
+	// Address listened on when none is given
+	DefaultAddr = "0.0.0.0:8080"
+	// Entries per directory listing page when none is given
+	DefaultPageSize = server.DefaultPageSize
+
+	ThemeBasic   = themes.ThemeBasic
+	ThemePretty  = themes.ThemePretty
+	ThemeGallery = themes.ThemeGallery
+
+	ChallengeHTTP01    = acme.ChallengeHTTP01
+	ChallengeTLSALPN01 = acme.ChallengeTLSALPN01
+
+	ClientAuthRequire  = server.ClientAuthRequire
+	ClientAuthOptional = server.ClientAuthOptional
+
+	SymlinksNever      = string(files.SymlinksNever)
+	SymlinksWithinRoot = string(files.SymlinksWithinRoot)
+	SymlinksAllowList  = string(files.SymlinksAllowList)
+	SymlinksShow       = string(files.SymlinksShow)
+
+	SizeUnitsSI     = string(files.SizeUnitsSI)
+	SizeUnitsBinary = string(files.SizeUnitsBinary)
+)
+
+var (
+	ErrAlreadyStarted = errors.New("server is already started")
+	ErrNotStarted     = errors.New("server is not started")
+)
+
+// Settings to obtain and renew certificates via ACME
+type ACMEConfig = acme.Config
+
+// Restricts a path prefix to clients with matching certificates, see [WithClientCertRule]
+type ClientCertRule = middlewares.ClientCertRule
+
+// Wraps the handler serving files, directory indexes or the proxy
+type Middleware func(http.Handler) http.Handler
+
+// An embeddable goserve server
+type Server struct {
+	config  server.ServerConfig
+	addrs   []string
+	handler http.Handler
+
+	mu      sync.Mutex
+	started bool
+}
+
+// Creates a server from the options.
+// Returns an error if an option is invalid, e.g. an unknown theme or a root directory that does not exist.
+func New(opts ...Option) (*Server, error) {
+	s := &Server{
+		config: server.ServerConfig{
+			RootDir:             ".",
+			DirViewTheme:        ThemePretty,
+			PageSize:            server.DefaultPageSize,
+			ClientAuth:          ClientAuthRequire,
+			ProxyHeadersEnabled: true,
+		},
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if !themes.Exists(s.config.DirViewTheme) {
+		return nil, fmt.Errorf("unknown theme '%s'", s.config.DirViewTheme)
+	}
+
+	if s.config.FS == nil {
+		rootDir, err := resolveRootDir(s.config.RootDir)
+		if err != nil {
+			return nil, err
+		}
+		s.config.RootDir = rootDir
+
+		for i, overlayDir := range s.config.OverlayDirs {
+			if s.config.OverlayDirs[i], err = resolveRootDir(overlayDir); err != nil {
+				return nil, err
+			}
+		}
+
+		for i, allowDir := range s.config.SymlinkAllowDirs {
+			if s.config.SymlinkAllowDirs[i], err = resolveRootDir(allowDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.setListenAddrs(); err != nil {
+		return nil, err
+	}
+
+	handler, err := s.config.Handler()
+	if err != nil {
+		return nil, err
+	}
+	s.handler = handler
+
+	return s, nil
+}
+
+// Handler serving the configured routes, to be mounted at "/" in an existing mux or server.
+// It does not need Start to be called, and is the same handler the listeners of Start serve.
+// Links in listings and asset URLs are absolute, so it cannot be mounted under a prefix with [http.StripPrefix].
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Listens on the configured addresses and starts serving in the background.
+// Returns once all listeners are bound, or the first error setting them up.
+// Background tasks such as certificate renewals stop when ctx is done, use Shutdown to stop serving.
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return ErrAlreadyStarted
+	}
+
+	if err := s.config.Start(ctx); err != nil {
+		return err
+	}
+	s.started = true
+
+	return nil
+}
+
+// Stops accepting connections and waits for in-flight requests until ctx is done.
+// Connections still open then are closed, and the context error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return ErrNotStarted
+	}
+
+	return s.config.Shutdown(ctx)
+}
+
+// Closes all listeners and connections immediately
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return ErrNotStarted
+	}
+
+	return s.config.Close()
+}
+
+// Addresses the server is listening on after Start, e.g. to find the port picked for "127.0.0.1:0"
+func (s *Server) Addrs() []net.Addr {
+	return s.config.Addrs()
+}
+
+// Reports errors of servers which stopped unexpectedly after Start
+func (s *Server) Errors() <-chan error {
+	return s.config.ServeErrors()
+}
+
+// Parses the addresses given to WithAddr, the first TCP one is used for HTTPS redirects and Alt-Svc
+func (s *Server) setListenAddrs() error {
+	addrs := s.addrs
+	if len(addrs) == 0 {
+		addrs = []string{DefaultAddr}
+	}
+
+	hostPortSet := false
+
+	for _, value := range addrs {
+		if addr, ok := listener.Parse(value); ok {
+			s.config.ListenAddrs = append(s.config.ListenAddrs, addr)
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(value)
+		if err != nil {
+			return fmt.Errorf("invalid address '%s': %w", value, err)
+		}
+
+		s.config.ListenAddrs = append(s.config.ListenAddrs, listener.Address{
+			Network:      listener.NetworkTCP,
+			Addr:         value,
+			PortFallback: s.config.PortFallback,
+		})
+
+		if !hostPortSet {
+			s.config.Host, s.config.Port = host, port
+			hostPortSet = true
+		}
+	}
+
+	return nil
+}
+
+func resolveRootDir(rootDir string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(rootDir))
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve root directory: %w", err)
+	}
+
+	return filepath.Abs(resolved)
+}
@@ -0,0 +1,427 @@
+package goserve
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+// Configures a [Server], see [New]
+type Option func(*Server) error
+
+// Addresses to listen on, as host:port, unix:/path/to.sock or systemd[:name].
+// Defaults to [DefaultAddr].
+func WithAddr(addrs ...string) Option {
+	return func(s *Server) error {
+		s.addrs = append(s.addrs, addrs...)
+		return nil
+	}
+}
+
+// Moves on to the next free port when the port of a TCP address is in use
+func WithPortFallback() Option {
+	return func(s *Server) error {
+		s.config.PortFallback = true
+		return nil
+	}
+}
+
+// File mode to set on Unix sockets
+func WithSocketMode(mode fs.FileMode) Option {
+	return func(s *Server) error {
+		s.config.SocketMode = mode
+		return nil
+	}
+}
+
+// Directory or archive (.zip, .tar, .tar.gz) to serve, defaults to the current directory
+func WithRootDir(dir string) Option {
+	return func(s *Server) error {
+		s.config.RootDir = dir
+		return nil
+	}
+}
+
+// Roots layered below the root directory, a path is served from the first root containing it.
+// Directory listings merge all roots and show which one each entry comes from.
+func WithOverlayDirs(dirs ...string) Option {
+	return func(s *Server) error {
+		s.config.OverlayDirs = append(s.config.OverlayDirs, dirs...)
+		return nil
+	}
+}
+
+// File system to serve instead of a directory, e.g. an [embed.FS].
+// Files should implement [io.Seeker] for range requests.
+func WithFS(fsys fs.FS) Option {
+	return func(s *Server) error {
+		s.config.FS = fsys
+		return nil
+	}
+}
+
+// Serves the tree of a git repository at ref instead of a directory, read from its object database.
+// Other refs can be requested with the "ref" query parameter. An empty ref serves HEAD.
+func WithGit(repoDir, ref string) Option {
+	return func(s *Server) error {
+		s.config.GitDir = repoDir
+		s.config.GitRef = ref
+		return nil
+	}
+}
+
+// Hides files and directories whose name starts with "."
+func WithHideDotfiles() Option {
+	return func(s *Server) error {
+		s.config.HideDotfiles = true
+		return nil
+	}
+}
+
+// Hides paths matching the patterns, in .gitignore syntax relative to the root.
+// Hidden paths are left out of listings and return 404.
+func WithExclude(patterns ...string) Option {
+	return func(s *Server) error {
+		s.config.Exclude = append(s.config.Exclude, patterns...)
+		return nil
+	}
+}
+
+// Hides paths ignored by .gitignore and .goserveignore files, and the .git directory
+func WithIgnoreFiles() Option {
+	return func(s *Server) error {
+		s.config.IgnoreFiles = true
+		return nil
+	}
+}
+
+// How symlinks are treated: [SymlinksNever], [SymlinksWithinRoot] (default), [SymlinksAllowList] or [SymlinksShow].
+// With [SymlinksAllowList], symlinks may also lead into allowDirs.
+func WithSymlinks(policy string, allowDirs ...string) Option {
+	return func(s *Server) error {
+		if !files.SymlinkPolicy(policy).Valid() {
+			return fmt.Errorf("unknown symlink policy '%s'", policy)
+		}
+
+		s.config.Symlinks = files.SymlinkPolicy(policy)
+		s.config.SymlinkAllowDirs = append(s.config.SymlinkAllowDirs, allowDirs...)
+		return nil
+	}
+}
+
+// Sets CORS headers on responses
+func WithCORS() Option {
+	return func(s *Server) error {
+		s.config.CorsEnabled = true
+		return nil
+	}
+}
+
+// Directory index page theme, [ThemePretty] by default
+func WithTheme(theme string) Option {
+	return func(s *Server) error {
+		s.config.DirViewTheme = theme
+		return nil
+	}
+}
+
+// Renders directory listings with a Go html/template file, or a directory with an index.html template and assets.
+// Search results and previews keep using the theme
+func WithIndexTemplate(path string) Option {
+	return func(s *Server) error {
+		s.config.IndexTemplate = path
+		return nil
+	}
+}
+
+// Units of sizes in directory listings, [SizeUnitsSI] (default) or [SizeUnitsBinary]
+func WithSizeUnits(units string) Option {
+	return func(s *Server) error {
+		if !files.SizeUnits(units).Valid() {
+			return fmt.Errorf("unknown size units '%s'", units)
+		}
+
+		s.config.SizeUnits = files.SizeUnits(units)
+		return nil
+	}
+}
+
+// Entries per directory listing page, [DefaultPageSize] by default. 0 lists every entry
+func WithPageSize(size int) Option {
+	return func(s *Server) error {
+		if size < 0 || size > files.MaxPerPage {
+			return fmt.Errorf("page size must be between 0 and %d", files.MaxPerPage)
+		}
+
+		s.config.PageSize = size
+		return nil
+	}
+}
+
+// Limits of ?search=, zero values keep the defaults of 16 levels, 1000 results and 10 seconds
+func WithSearchLimits(maxDepth, maxResults int, timeout time.Duration) Option {
+	return func(s *Server) error {
+		if maxDepth < 0 || maxResults < 0 || timeout < 0 {
+			return errors.New("search limits cannot be negative")
+		}
+
+		s.config.SearchMaxDepth = maxDepth
+		s.config.SearchMaxResults = maxResults
+		s.config.SearchTimeout = timeout
+		return nil
+	}
+}
+
+// Indexes text files no larger than maxFileSize in the background, following changes of root directories,
+// or rescanning them every interval where they cannot be watched, to search their contents with ?search=...&content=1. Zero values keep the defaults of 1 MiB and 30 seconds.
+// Indexing starts with [Server.Start] and stops when its context is done.
+// Without Start, it starts with the first content search through [Server.Handler] and runs until the program exits
+func WithTextIndex(maxFileSize int64, interval time.Duration) Option {
+	return func(s *Server) error {
+		if maxFileSize < 0 || interval < 0 {
+			return errors.New("text index limits cannot be negative")
+		}
+
+		s.config.TextIndex = true
+		s.config.TextIndexMaxSize = maxFileSize
+		s.config.TextIndexInterval = interval
+		return nil
+	}
+}
+
+// Serves HTTPS with the certificate and key files.
+// Empty paths use a self-signed certificate.
+func WithHTTPS(certPath, keyPath string) Option {
+	return func(s *Server) error {
+		if (certPath == "") != (keyPath == "") {
+			return errors.New("both cert and key paths must be provided, or both must be empty to use a self-signed certificate")
+		}
+
+		s.config.HttpsEnabled = true
+		s.config.CertPath = certPath
+		s.config.KeyPath = keyPath
+		return nil
+	}
+}
+
+// Certificate for a hostname, selected by SNI. Implies HTTPS.
+// Host can be a wildcard, e.g. "*.example.com".
+func WithSNICert(host, certPath, keyPath string) Option {
+	return func(s *Server) error {
+		s.config.SNICerts = append(s.config.SNICerts, server.SNICert{Host: host, CertPath: certPath, KeyPath: keyPath})
+		return nil
+	}
+}
+
+// Also serves plain HTTP on addr while HTTPS is enabled.
+// A non-zero redirect status code (e.g. 308) redirects plain HTTP requests to HTTPS instead.
+// With [WithClientCA], plain HTTP requests are always redirected, with 308 if the status code is 0.
+func WithPlainHTTP(addr string, redirectStatus int) Option {
+	return func(s *Server) error {
+		switch redirectStatus {
+		case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return fmt.Errorf("invalid redirect status code %d", redirectStatus)
+		}
+
+		s.config.HttpAddr = addr
+		s.config.HttpRedirect = redirectStatus
+		return nil
+	}
+}
+
+// Sets the Strict-Transport-Security header with this max age on HTTPS responses
+func WithHSTS(maxAge time.Duration) Option {
+	return func(s *Server) error {
+		s.config.HstsMaxAge = maxAge
+		return nil
+	}
+}
+
+// Also serves HTTP/3 on the same addresses while HTTPS is enabled
+func WithHTTP3() Option {
+	return func(s *Server) error {
+		s.config.Http3Enabled = true
+		return nil
+	}
+}
+
+// Accepts HTTP/2 without TLS on plain HTTP listeners
+func WithH2C() Option {
+	return func(s *Server) error {
+		s.config.H2cEnabled = true
+		return nil
+	}
+}
+
+// Obtains and renews certificates via ACME. Implies HTTPS.
+// addr is where HTTP-01 challenges are answered, empty for ":80".
+func WithACME(config ACMEConfig, addr string) Option {
+	return func(s *Server) error {
+		if len(config.Domains) == 0 {
+			return errors.New("at least one domain is required for ACME")
+		}
+
+		s.config.AcmeEnabled = true
+		s.config.Acme = config
+		s.config.AcmeHTTPAddr = addr
+		return nil
+	}
+}
+
+// Verifies client certificates against the CA certificates in the PEM file. Implies HTTPS.
+// Mode is [ClientAuthRequire] or [ClientAuthOptional].
+func WithClientCA(caPath, mode string) Option {
+	return func(s *Server) error {
+		if mode != ClientAuthRequire && mode != ClientAuthOptional {
+			return fmt.Errorf("invalid client auth mode '%s'", mode)
+		}
+
+		s.config.ClientCAPath = caPath
+		s.config.ClientAuth = mode
+		return nil
+	}
+}
+
+// Only allows clients with a certificate matching one of the names to access the path prefix.
+// The prefix is matched by whole path segments, and names are glob patterns matched against the certificate's common name and SANs.
+// Requires [WithClientCA], New returns an error otherwise.
+func WithClientCertRule(pathPrefix string, allowed ...string) Option {
+	return func(s *Server) error {
+		s.config.ClientCertRules = append(s.config.ClientCertRules, ClientCertRule{PathPrefix: pathPrefix, Allowed: allowed})
+		return nil
+	}
+}
+
+// Proxies requests to the target URL instead of serving files
+func WithProxy(targetURL string) Option {
+	return func(s *Server) error {
+		s.config.ProxyToAddr = targetURL
+		return nil
+	}
+}
+
+// Whether to include X-Forwarded-For and X-Forwarded-Proto headers in proxied requests, enabled by default
+func WithProxyHeaders(enabled bool) Option {
+	return func(s *Server) error {
+		s.config.ProxyHeadersEnabled = enabled
+		return nil
+	}
+}
+
+// Passes redirects from the proxy target to the client instead of following them
+func WithProxyIgnoreRedirect() Option {
+	return func(s *Server) error {
+		s.config.ProxyIgnoreRedirect = true
+		return nil
+	}
+}
+
+// Talks to the proxy target with HTTP/2 without TLS
+func WithProxyH2C() Option {
+	return func(s *Server) error {
+		s.config.ProxyH2c = true
+		return nil
+	}
+}
+
+// Minimum and maximum TLS versions, e.g. [crypto/tls.VersionTLS12]. Zero leaves the Go default.
+func WithTLSVersions(minVersion, maxVersion uint16) Option {
+	return func(s *Server) error {
+		s.config.TLSMinVersion = minVersion
+		s.config.TLSMaxVersion = maxVersion
+		return nil
+	}
+}
+
+// Allowed cipher suites for TLS 1.2 and below
+func WithTLSCipherSuites(ids ...uint16) Option {
+	return func(s *Server) error {
+		s.config.TLSCipherSuites = ids
+		return nil
+	}
+}
+
+// Key exchange curves in order of preference
+func WithTLSCurves(curves ...tls.CurveID) Option {
+	return func(s *Server) error {
+		s.config.TLSCurves = curves
+		return nil
+	}
+}
+
+// ALPN protocols to advertise, defaults to "h2" and "http/1.1"
+func WithTLSNextProtos(protos ...string) Option {
+	return func(s *Server) error {
+		s.config.TLSNextProtos = protos
+		return nil
+	}
+}
+
+// Disables TLS session ticket resumption
+func WithoutTLSSessionTickets() Option {
+	return func(s *Server) error {
+		s.config.TLSSessionTicketsDisabled = true
+		return nil
+	}
+}
+
+// Rotates session ticket keys at this interval
+func WithTLSTicketRotation(interval time.Duration) Option {
+	return func(s *Server) error {
+		s.config.TLSSessionTicketRotation = interval
+		return nil
+	}
+}
+
+// Server timeouts, zero values mean no timeout
+func WithTimeouts(read, readHeader, write, idle time.Duration) Option {
+	return func(s *Server) error {
+		s.config.ReadTimeout = read
+		s.config.ReadHeaderTimeout = readHeader
+		s.config.WriteTimeout = write
+		s.config.IdleTimeout = idle
+		return nil
+	}
+}
+
+// Maximum size of request headers in bytes
+func WithMaxHeaderBytes(size int) Option {
+	return func(s *Server) error {
+		s.config.MaxHeaderBytes = size
+		return nil
+	}
+}
+
+// Maximum number of connections open at once, further ones wait
+func WithMaxConns(count int) Option {
+	return func(s *Server) error {
+		s.config.MaxConns = count
+		return nil
+	}
+}
+
+// Prints a QR code of the LAN URL on Start
+func WithQRCode() Option {
+	return func(s *Server) error {
+		s.config.QRCode = true
+		return nil
+	}
+}
+
+// Wraps the route handler with middlewares, the first one runs first.
+// They run after goserve's own middlewares such as CORS, and before files are served or proxied.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(s *Server) error {
+		for _, middleware := range middlewares {
+			s.config.Middlewares = append(s.config.Middlewares, middleware)
+		}
+		return nil
+	}
+}
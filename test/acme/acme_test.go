@@ -0,0 +1,164 @@
+package acme_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/ssl"
+)
+
+func TestNewRejectsUnknownChallenge(t *testing.T) {
+	_, err := acme.New(acme.Config{Domains: []string{"app.test"}, Challenge: "dns-01"}, ssl.NewCertStore())
+	if err == nil {
+		t.Fatalf("New() accepted an unsupported challenge type")
+	}
+}
+
+func TestNewRequiresDomain(t *testing.T) {
+	_, err := acme.New(acme.Config{}, ssl.NewCertStore())
+	if err == nil {
+		t.Fatalf("New() accepted a config without domains")
+	}
+}
+
+func TestStartLoadsCachedCertificate(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	keyPair, err := ssl.NewKeys(365 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeys() returned error: %v", err)
+	}
+
+	// Named after all domains, sorted
+	if err := os.WriteFile(filepath.Join(cacheDir, "app.test+www.app.test.crt"), keyPair.Cert.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write cert: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(cacheDir, "app.test+www.app.test.key"), keyPair.Key.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	store := ssl.NewCertStore()
+
+	manager, err := acme.New(acme.Config{
+		// Nothing should be requested from the directory while the cached certificate is valid
+		DirectoryURL: "http://127.0.0.1:0/dir",
+		Domains:      []string{"www.app.test", "app.test"},
+		CacheDir:     cacheDir,
+	}, store)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	manager.Start(ctx)
+
+	for _, domain := range []string{"app.test", "www.app.test"} {
+		cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: domain})
+		if err != nil {
+			t.Fatalf("GetCertificate(%q) returned error: %v", domain, err)
+		}
+
+		if cert.Leaf == nil || cert.Leaf.NotAfter.Before(time.Now()) {
+			t.Fatalf("GetCertificate(%q) returned an unexpected certificate", domain)
+		}
+	}
+}
+
+func TestHTTPHandlerPassesThrough(t *testing.T) {
+	manager, err := acme.New(acme.Config{Domains: []string{"app.test"}}, ssl.NewCertStore())
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	handler := manager.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("Expected request to be passed through, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown token, got status %d", rec.Code)
+	}
+}
+
+func TestObtainAndRenewCertificate(t *testing.T) {
+	// The first certificate is due for renewal 2 seconds after it is issued
+	ca := newTestCA(t, time.Hour+2*time.Second, 90*24*time.Hour)
+	cacheDir := t.TempDir()
+
+	// A certificate cached for only one of the domains is not used
+	keyPair, err := ssl.NewKeys(365 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeys() returned error: %v", err)
+	}
+	os.WriteFile(filepath.Join(cacheDir, "app.test.crt"), keyPair.Cert.Bytes(), 0600)
+	os.WriteFile(filepath.Join(cacheDir, "app.test.key"), keyPair.Key.Bytes(), 0600)
+
+	store := ssl.NewCertStore()
+	manager, err := acme.New(acme.Config{
+		DirectoryURL: ca.directoryURL(),
+		Domains:      []string{"app.test", "www.app.test"},
+		CacheDir:     cacheDir,
+		RenewBefore:  time.Hour,
+	}, store)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	ca.challengeHandler = manager.HTTPHandler(http.NotFoundHandler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	manager.Start(ctx)
+
+	// Order, challenges and finalisation, twice
+	deadline := time.Now().Add(15 * time.Second)
+	for ca.issued() < 2 || serialOf(store, "www.app.test") != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the certificate to be issued and renewed, got %d certificates", ca.issued())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	for _, domain := range []string{"app.test", "www.app.test"} {
+		cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: domain})
+		if err != nil {
+			t.Fatalf("GetCertificate(%q) returned error: %v", domain, err)
+		}
+
+		if err := cert.Leaf.VerifyHostname(domain); err != nil || time.Until(cert.Leaf.NotAfter) < 30*24*time.Hour {
+			t.Errorf("GetCertificate(%q) should return the renewed certificate, got one expiring %s (%v)", domain, cert.Leaf.NotAfter, err)
+		}
+	}
+
+	// Saved for the next start, named after all domains
+	cached, err := tls.LoadX509KeyPair(filepath.Join(cacheDir, "app.test+www.app.test.crt"), filepath.Join(cacheDir, "app.test+www.app.test.key"))
+	if err != nil || cached.Leaf.SerialNumber.Int64() != 3 {
+		t.Errorf("Expected the renewed certificate to be saved, got %v", err)
+	}
+}
+
+func serialOf(store *ssl.CertStore, domain string) int64 {
+	cert := store.Lookup(domain)
+	if cert == nil || cert.Leaf == nil {
+		return 0
+	}
+
+	return cert.Leaf.SerialNumber.Int64()
+}
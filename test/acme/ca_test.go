@@ -0,0 +1,326 @@
+package acme_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A minimal ACME server (RFC 8555) issuing certificates for HTTP-01 challenges.
+// Signatures of requests are not checked, only their payloads are read.
+type testCA struct {
+	t      *testing.T
+	server *httptest.Server
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	// Solves HTTP-01 challenges, e.g. the handler of the server being issued certificates
+	challengeHandler http.Handler
+	// Validity of the certificates issued for each order, the last one is reused
+	validity []time.Duration
+
+	mu     sync.Mutex
+	nonce  int
+	orders []*testOrder
+	authzs map[string]*testAuthz
+}
+
+type testOrder struct {
+	identifiers []map[string]string
+	authzs      []string
+	cert        []byte
+}
+
+type testAuthz struct {
+	domain string
+	token  string
+	valid  bool
+}
+
+func newTestCA(t *testing.T, validity ...time.Duration) *testCA {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "goserve test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+
+	ca := &testCA{t: t, caKey: caKey, caCert: caCert, validity: validity, authzs: make(map[string]*testAuthz)}
+	ca.server = httptest.NewServer(http.HandlerFunc(ca.serveHTTP))
+	t.Cleanup(ca.server.Close)
+
+	return ca
+}
+
+func (ca *testCA) directoryURL() string {
+	return ca.server.URL + "/dir"
+}
+
+// Gets the number of orders finalised with a certificate
+func (ca *testCA) issued() int {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	issued := 0
+	for _, order := range ca.orders {
+		if order.cert != nil {
+			issued++
+		}
+	}
+
+	return issued
+}
+
+func (ca *testCA) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	ca.mu.Lock()
+	ca.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", ca.nonce))
+	ca.mu.Unlock()
+
+	if r.URL.Path == "/dir" {
+		ca.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   ca.server.URL + "/nonce",
+			"newAccount": ca.server.URL + "/account",
+			"newOrder":   ca.server.URL + "/order",
+		})
+		return
+	}
+
+	if r.URL.Path == "/nonce" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	kind, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	switch kind {
+	case "account":
+		w.Header().Set("Location", ca.server.URL+"/account/1")
+		ca.writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+	case "order":
+		if id == "" {
+			ca.newOrder(w, payload)
+			return
+		}
+
+		ca.writeOrder(w, http.StatusOK, id)
+	case "authz":
+		ca.writeAuthz(w, id)
+	case "challenge":
+		ca.validate(w, id)
+	case "finalize":
+		ca.finalize(w, id, payload)
+	case "cert":
+		ca.writeCert(w, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (ca *testCA) newOrder(w http.ResponseWriter, payload []byte) {
+	var req struct {
+		Identifiers []map[string]string `json:"identifiers"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order := &testOrder{identifiers: req.Identifiers}
+	for _, identifier := range req.Identifiers {
+		id := fmt.Sprintf("%d-%s", len(ca.orders), identifier["value"])
+		ca.authzs[id] = &testAuthz{domain: identifier["value"], token: "token-" + id}
+		order.authzs = append(order.authzs, ca.server.URL+"/authz/"+id)
+	}
+	ca.orders = append(ca.orders, order)
+
+	ca.writeOrder(w, http.StatusCreated, fmt.Sprint(len(ca.orders)-1))
+}
+
+func (ca *testCA) writeOrder(w http.ResponseWriter, status int, id string) {
+	var index int
+	if _, err := fmt.Sscan(id, &index); err != nil || index >= len(ca.orders) {
+		http.NotFound(w, nil)
+		return
+	}
+	order := ca.orders[index]
+
+	orderStatus := "ready"
+	for _, authzURL := range order.authzs {
+		if !ca.authzs[authzURL[strings.LastIndex(authzURL, "/")+1:]].valid {
+			orderStatus = "pending"
+		}
+	}
+
+	body := map[string]any{
+		"status":         orderStatus,
+		"identifiers":    order.identifiers,
+		"authorizations": order.authzs,
+		"finalize":       ca.server.URL + "/finalize/" + id,
+	}
+	if order.cert != nil {
+		body["status"] = "valid"
+		body["certificate"] = ca.server.URL + "/cert/" + id
+	}
+
+	w.Header().Set("Location", ca.server.URL+"/order/"+id)
+	ca.writeJSON(w, status, body)
+}
+
+func (ca *testCA) writeAuthz(w http.ResponseWriter, id string) {
+	authz, ok := ca.authzs[id]
+	if !ok {
+		http.NotFound(w, nil)
+		return
+	}
+
+	status := "pending"
+	if authz.valid {
+		status = "valid"
+	}
+
+	ca.writeJSON(w, http.StatusOK, map[string]any{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": authz.domain},
+		"challenges": []map[string]string{
+			{"type": "http-01", "url": ca.server.URL + "/challenge/" + id, "token": authz.token, "status": status},
+			{"type": "dns-01", "url": ca.server.URL + "/challenge/dns-" + id, "token": authz.token, "status": "pending"},
+		},
+	})
+}
+
+// Fetches the key authorization from the challenge handler, as a CA would from the domain
+func (ca *testCA) validate(w http.ResponseWriter, id string) {
+	authz, ok := ca.authzs[id]
+	if !ok {
+		http.NotFound(w, nil)
+		return
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://"+authz.domain+"/.well-known/acme-challenge/"+authz.token, nil)
+	ca.challengeHandler.ServeHTTP(rec, req)
+
+	authz.valid = rec.Code == http.StatusOK && strings.HasPrefix(rec.Body.String(), authz.token+".")
+	if !authz.valid {
+		ca.t.Errorf("Challenge for %s answered %d with %q", authz.domain, rec.Code, rec.Body.String())
+	}
+
+	ca.writeJSON(w, http.StatusOK, map[string]string{"type": "http-01", "url": ca.server.URL + "/challenge/" + id, "token": authz.token, "status": "valid"})
+}
+
+func (ca *testCA) finalize(w http.ResponseWriter, id string, payload []byte) {
+	var index int
+	if _, err := fmt.Sscan(id, &index); err != nil || index >= len(ca.orders) {
+		http.NotFound(w, nil)
+		return
+	}
+
+	var req struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	csrDER, err := base64.RawURLEncoding.DecodeString(req.CSR)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validity := ca.validity[min(index, len(ca.validity)-1)]
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(index) + 2),
+		Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.caCert, csr.PublicKey, ca.caKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ca.orders[index].cert = der
+	ca.writeOrder(w, http.StatusOK, id)
+}
+
+func (ca *testCA) writeCert(w http.ResponseWriter, id string) {
+	var index int
+	if _, err := fmt.Sscan(id, &index); err != nil || index >= len(ca.orders) || ca.orders[index].cert == nil {
+		http.NotFound(w, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.orders[index].cert})
+	pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.caCert.Raw})
+}
+
+func (ca *testCA) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,193 @@
+package custom_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes/custom"
+)
+
+var customTestFS = fstest.MapFS{
+	"docs/api/spec.yaml": {Data: []byte("openapi: 3.0.0")},
+	"docs/api/README.md": {Data: []byte("# API <script>alert(1)</script>")},
+	"docs/api/v1/a.txt":  {Data: []byte("a")},
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("Cannot create directory: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("Cannot write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func get(t *testing.T, config *server.ServerConfig, url string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+	return rec
+}
+
+func TestTemplateFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"listing.html": `{{range .Breadcrumbs}}[{{.Name}} {{.URL}}]{{end}}
+{{range .Entries}}<a href="{{.URL}}">{{.Name}}</a> dir={{.Dir}} size={{.Size}}
+{{end}}{{with .Readme}}{{.HTML}}{{end}}`,
+	})
+
+	config := &server.ServerConfig{FS: customTestFS, IndexTemplate: filepath.Join(dir, "listing.html")}
+	body := get(t, config, "/docs/api/").Body.String()
+
+	for _, expected := range []string{
+		"[/ /][docs /docs/][api /docs/api/]",
+		`<a href="/docs/api/v1/">v1/</a> dir=true size=0`,
+		`<a href="/docs/api/spec.yaml">spec.yaml</a> dir=false size=14`,
+		"<h1",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Template should render %q, got:\n%s", expected, body)
+		}
+	}
+
+	if strings.Contains(body, "<script>") {
+		t.Errorf("Readme should be sanitised, got:\n%s", body)
+	}
+}
+
+func TestTemplateDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":    `<link rel="stylesheet" href="{{asset "css/site.css"}}" nonce="{{.Nonce}}">{{template "entries.html" .}}`,
+		"entries.html":  `{{range .Entries}}{{.Name}};{{end}}`,
+		"css/site.css":  "body { color: teal; }",
+		"img/logo.svg":  "<svg></svg>",
+		"partials/x.md": "not a template",
+	})
+
+	config := &server.ServerConfig{FS: customTestFS, IndexTemplate: dir}
+	rec := get(t, config, "/docs/api/")
+	body := rec.Body.String()
+
+	if !strings.Contains(body, "v1/;README.md;spec.yaml;") {
+		t.Errorf("Template should render the entries partial, got:\n%s", body)
+	}
+
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(rec.Header().Get("Content-Security-Policy"))
+	if nonce == nil || !strings.Contains(body, `nonce="`+nonce[1]+`"`) {
+		t.Errorf("Template should get the CSP nonce, got:\n%s", body)
+	}
+
+	assetURL := regexp.MustCompile(`href="(/[^"]+site\.css)"`).FindStringSubmatch(body)
+	if assetURL == nil {
+		t.Fatalf("Template should link the asset, got:\n%s", body)
+	}
+
+	rec = get(t, config, assetURL[1])
+	if rec.Body.String() != "body { color: teal; }" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("Asset returned %q as %s", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestReloadTemplateAssets(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":   `<link rel="stylesheet" href="{{asset "site.css"}}">`,
+		"site.css":     "body { color: olive; }",
+		"img/logo.svg": "<svg></svg>",
+	})
+
+	first := get(t, &server.ServerConfig{FS: customTestFS, IndexTemplate: dir}, "/").Body.String()
+
+	// Assets are served while another server loads the same theme
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			get(t, &server.ServerConfig{FS: customTestFS}, "/_goserveass/missing.css")
+		}
+	}()
+
+	second := get(t, &server.ServerConfig{FS: customTestFS, IndexTemplate: dir}, "/").Body.String()
+	<-done
+
+	if first != second {
+		t.Errorf("Loading a theme again should reuse its asset URLs, got %q and %q", first, second)
+	}
+}
+
+func TestLoadInvalidTemplate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"broken.html": "{{range .Entries}}",
+		"theme/a.css": "",
+	})
+
+	for _, name := range []string{"broken.html", "theme", "missing.html"} {
+		if _, err := custom.Load(filepath.Join(dir, name)); !errors.Is(err, custom.ErrInvalidTemplate) {
+			t.Errorf("Load(%s) returned %v, expected ErrInvalidTemplate", name, err)
+		}
+	}
+}
+
+func TestTemplateExecutionError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"listing.html": `<p>{{asset "missing.css"}}</p>`})
+
+	config := &server.ServerConfig{FS: customTestFS, IndexTemplate: filepath.Join(dir, "listing.html")}
+	rec := get(t, config, "/")
+
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "<p>") {
+		t.Errorf("Failing template returned status %d and %q, expected 500 without a partial page", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTemplateAssetRelativeURLs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":         `<link rel="stylesheet" href="{{asset "css/site.css"}}">`,
+		"css/site.css":       "@font-face { src: url(../fonts/brand.woff2); } body { background: url(bg.png); }",
+		"css/bg.png":         "png",
+		"fonts/brand.woff2":  "woff2",
+		"fonts/unused.woff2": "unused",
+	})
+
+	config := &server.ServerConfig{FS: customTestFS, IndexTemplate: dir}
+	rec := get(t, config, "/")
+
+	if !strings.Contains(rec.Header().Get("Content-Security-Policy"), "font-src 'self'") {
+		t.Errorf("Listings should allow fonts of the theme, got CSP %q", rec.Header().Get("Content-Security-Policy"))
+	}
+
+	assetURL := regexp.MustCompile(`href="(/[^"]+/css/site\.css)"`).FindStringSubmatch(rec.Body.String())
+	if assetURL == nil {
+		t.Fatalf("Template should link the asset under its path, got:\n%s", rec.Body.String())
+	}
+
+	base, _ := url.Parse(assetURL[1])
+	for ref, expected := range map[string]string{"../fonts/brand.woff2": "woff2", "bg.png": "png"} {
+		target := base.ResolveReference(&url.URL{Path: ref}).Path
+		if body := get(t, config, target).Body.String(); body != expected {
+			t.Errorf("Asset %s referenced as %s returned %q, expected %q", target, ref, body, expected)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package files_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ducng99/goserve/internal/files"
+)
+
+var archiveFiles = map[string]string{
+	"readme.txt":         "Read me first",
+	"docs/guide.txt":     "0123456789abcdefghij",
+	"docs/api/index.txt": "API docs",
+}
+
+func createZip(t *testing.T, method uint16) string {
+	archivePath := filepath.Join(t.TempDir(), "release.zip")
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	writer := zip.NewWriter(f)
+	for name, content := range archiveFiles {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now()})
+		if err != nil {
+			t.Fatalf("Failed to add %s to archive: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+
+	return archivePath
+}
+
+func createTar(t *testing.T, name string, compress bool) string {
+	archivePath := filepath.Join(t.TempDir(), name)
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if compress {
+		gzipWriter := gzip.NewWriter(f)
+		defer gzipWriter.Close()
+		w = gzipWriter
+	}
+
+	writer := tar.NewWriter(w)
+	defer writer.Close()
+
+	for name, content := range archiveFiles {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add %s to archive: %v", name, err)
+		}
+		writer.Write([]byte(content))
+	}
+
+	return archivePath
+}
+
+func TestArchiveFS(t *testing.T) {
+	archives := map[string]string{
+		"zip deflate": createZip(t, zip.Deflate),
+		"zip store":   createZip(t, zip.Store),
+		"tar":         createTar(t, "release.tar", false),
+		"tar.gz":      createTar(t, "release.tar.gz", true),
+	}
+
+	expected := make([]string, 0, len(archiveFiles))
+	for name, content := range archiveFiles {
+		expected = append(expected, name, content)
+	}
+
+	for kind, archivePath := range archives {
+		archive, err := files.OpenArchive(archivePath)
+		if err != nil {
+			t.Fatalf("%s: OpenArchive() returned error: %v", kind, err)
+		}
+		defer archive.Close()
+
+		// Checks reading, seeking and listing of all files and implied directories
+		if err := fstest.TestFS(archive, "readme.txt", "docs/guide.txt", "docs/api/index.txt"); err != nil {
+			t.Errorf("%s: %v", kind, err)
+		}
+
+		pathType, err := files.GetPathType(archive, "docs/api")
+		if err != nil || pathType != files.PathTypeDirectory {
+			t.Errorf("%s: Expected docs/api to be a directory, got %v (%v)", kind, pathType, err)
+		}
+	}
+}
+
+func TestArchiveConcurrentListings(t *testing.T) {
+	archive, err := files.OpenArchive(createZip(t, zip.Deflate))
+	if err != nil {
+		t.Fatalf("OpenArchive() returned error: %v", err)
+	}
+	defer archive.Close()
+
+	// Listings share the children of the directory entries, run with -race to check they are only read
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			entries, err := fs.ReadDir(archive, "docs")
+			if err != nil || len(entries) != 2 || entries[0].Name() != "api" || entries[1].Name() != "guide.txt" {
+				t.Errorf("Expected docs to list api and guide.txt, got %v (%v)", entries, err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestOpenArchiveUnsupported(t *testing.T) {
+	if _, err := files.OpenArchive(filepath.Join(RootDir, File1Path)); err == nil {
+		t.Fatalf("OpenArchive() of a text file should return an error")
+	}
+}
+
+func TestServerArchiveRangeRequest(t *testing.T) {
+	for _, archivePath := range []string{createZip(t, zip.Deflate), createTar(t, "release.tgz", true)} {
+		ts := createTestServer(t, archivePath)
+
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/docs/guide.txt", nil)
+		req.Header.Set("Range", "bytes=10-14")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to get file in archive: %v", err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusPartialContent || string(body) != "abcde" {
+			t.Errorf("%s: Expected 206 with %q, got %d with %q", archivePath, "abcde", resp.StatusCode, body)
+		}
+
+		resp, err = http.Get(ts.URL + "/docs/")
+		if err != nil {
+			t.Fatalf("Failed to get directory in archive: %v", err)
+		}
+
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "guide.txt") {
+			t.Errorf("%s: Expected listing with guide.txt, got %d", archivePath, resp.StatusCode)
+		}
+	}
+}
+
+func TestSanitisePathFS(t *testing.T) {
+	fsys := fstest.MapFS{"dir/file.txt": {Data: []byte("content")}}
+
+	tests := map[string]string{
+		"/":                    ".",
+		"/dir/file.txt":        "dir/file.txt",
+		"/../dir/./file.txt":   "dir/file.txt",
+		"/dir/../dir/file.txt": "dir/file.txt",
+	}
+
+	for urlPath, expected := range tests {
+		name, err := files.SanitisePathFS(fsys, urlPath)
+		if err != nil || name != expected {
+			t.Errorf("SanitisePathFS(%q) returned %q (%v), expected %q", urlPath, name, err, expected)
+		}
+	}
+
+	if _, err := files.SanitisePathFS(fsys, "/missing.txt"); err != files.ErrorSanitiseNotExists {
+		t.Errorf("SanitisePathFS() of a missing file returned %v, expected ErrorSanitiseNotExists", err)
+	}
+}
+
+func TestOSFSRefusesSymlinkOutsideRoot(t *testing.T) {
+	osfs := files.NewOSFS(RootDir)
+
+	if _, err := fs.Stat(osfs, FileSymlinkInaccessible); err == nil {
+		t.Fatalf("Stat() of a symlink leading out of the root should fail")
+	}
+
+	if _, err := fs.Stat(osfs, File1Symlink); err != nil {
+		t.Errorf("Stat() of a symlink within the root returned error: %v", err)
+	}
+}
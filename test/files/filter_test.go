@@ -0,0 +1,213 @@
+package files_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+func createFilterTestDir(t *testing.T) string {
+	rootDir := t.TempDir()
+
+	testFiles := map[string]string{
+		".env":               "SECRET=1",
+		".git/config":        "[core]",
+		".gitignore":         "/build/\n*.tmp\n",
+		".goserveignore":     "drafts/\n",
+		"index.html":         "home",
+		"notes.txt.swp":      "swap",
+		"debug.log":          "debug",
+		"keep.log":           "keep",
+		"build/app.js":       "built",
+		"src/app.go":         "package main",
+		"src/cache.tmp":      "cache",
+		"src/.gitignore":     "!cache.tmp\ngenerated/\n",
+		"src/generated/a.go": "generated",
+		"src/build/b.go":     "not the root build",
+		"docs/drafts/x.md":   "draft",
+		"docs/guide.md":      "guide",
+	}
+
+	for name, content := range testFiles {
+		filePath := filepath.Join(rootDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	return rootDir
+}
+
+func TestFilterFS(t *testing.T) {
+	rootDir := createFilterTestDir(t)
+
+	tests := []struct {
+		name    string
+		options files.FilterOptions
+		visible []string
+		hidden  []string
+	}{
+		{
+			name:    "dotfiles",
+			options: files.FilterOptions{HideDotfiles: true},
+			visible: []string{"index.html", "build/app.js", "src/cache.tmp"},
+			hidden:  []string{".env", ".git/config", ".gitignore", "src/.gitignore"},
+		},
+		{
+			name:    "exclude",
+			options: files.FilterOptions{Exclude: []string{"*.swp", "*.log", "!keep.log", "/docs/drafts/"}},
+			visible: []string{"keep.log", ".env", "docs/guide.md"},
+			hidden:  []string{"notes.txt.swp", "debug.log", "docs/drafts/x.md"},
+		},
+		{
+			name:    "ignore files",
+			options: files.FilterOptions{IgnoreFiles: true},
+			visible: []string{".env", ".gitignore", "src/app.go", "src/build/b.go", "src/cache.tmp", "docs/guide.md"},
+			hidden:  []string{".git/config", ".goserveignore", "build/app.js", "src/generated/a.go", "docs/drafts/x.md"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := files.NewFilter(test.options)
+			if err != nil {
+				t.Fatalf("NewFilter() returned error: %v", err)
+			}
+			fsys := filter.FS(files.NewOSFS(rootDir))
+
+			for _, name := range test.visible {
+				if _, err := fs.ReadFile(fsys, name); err != nil {
+					t.Errorf("%s should be visible, got %v", name, err)
+				}
+			}
+
+			for _, name := range test.hidden {
+				if _, err := fs.ReadFile(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%s should be hidden, got %v", name, err)
+				}
+
+				entries, err := fs.ReadDir(fsys, filepath.ToSlash(filepath.Dir(name)))
+				if err != nil {
+					continue
+				}
+				if slices.ContainsFunc(entries, func(entry fs.DirEntry) bool { return entry.Name() == filepath.Base(name) }) {
+					t.Errorf("%s should not be listed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterFSSymlinkTargets(t *testing.T) {
+	rootDir := createFilterTestDir(t)
+
+	links := map[string]string{
+		"docs/env":    "../.env",
+		"docs/debug":  "../debug.log",
+		"docs/build":  "../build",
+		"docs/readme": "../index.html",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(rootDir, filepath.FromSlash(name))); err != nil {
+			t.Skipf("Cannot create symlinks: %v", err)
+		}
+	}
+
+	filter, err := files.NewFilter(files.FilterOptions{HideDotfiles: true, Exclude: []string{"*.log"}, IgnoreFiles: true})
+	if err != nil {
+		t.Fatalf("NewFilter() returned error: %v", err)
+	}
+	fsys := filter.FS(files.NewOSFS(rootDir).WithSymlinks(files.SymlinksWithinRoot))
+
+	for _, name := range []string{"docs/env", "docs/debug", "docs/build", "docs/build/app.js"} {
+		if _, err := fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s leads to a hidden path and should be hidden, got %v", name, err)
+		}
+	}
+
+	if content, err := fs.ReadFile(fsys, "docs/readme"); err != nil || string(content) != "home" {
+		t.Errorf("docs/readme leads to a visible file and should be readable, got %q, %v", content, err)
+	}
+
+	entries, err := fs.ReadDir(fsys, "docs")
+	if err != nil {
+		t.Fatalf("ReadDir() returned error: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !slices.Equal(names, []string{"guide.md", "readme"}) {
+		t.Errorf("Expected only guide.md and readme to be listed, got %v", names)
+	}
+}
+
+func TestFilterFSGitCaseInsensitive(t *testing.T) {
+	fsys := fstest.MapFS{
+		".GIT/config":    {Data: []byte("[core]")},
+		".Git/HEAD":      {Data: []byte("ref: refs/heads/main")},
+		".GoserveIgnore": {Data: []byte("drafts/")},
+		"index.html":     {Data: []byte("home")},
+	}
+
+	filter, err := files.NewFilter(files.FilterOptions{IgnoreFiles: true})
+	if err != nil {
+		t.Fatalf("NewFilter() returned error: %v", err)
+	}
+	filtered := filter.FS(fsys)
+
+	for _, name := range []string{".GIT/config", ".Git/HEAD", ".GoserveIgnore"} {
+		if _, err := fs.ReadFile(filtered, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s should be hidden, got %v", name, err)
+		}
+	}
+
+	entries, err := fs.ReadDir(filtered, ".")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "index.html" {
+		t.Errorf("Expected only index.html to be listed, got %v, %v", entries, err)
+	}
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	if _, err := files.NewFilter(files.FilterOptions{Exclude: []string{""}}); err == nil {
+		t.Errorf("NewFilter() should return an error for an empty pattern")
+	}
+}
+
+func TestServerHidesExcludedPaths(t *testing.T) {
+	config := server.ServerConfig{RootDir: createFilterTestDir(t), HideDotfiles: true, Exclude: []string{"*.log"}}
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	for path, status := range map[string]int{
+		"/src/app.go":  http.StatusOK,
+		"/.env":        http.StatusNotFound,
+		"/.git/":       http.StatusNotFound,
+		"/.git/config": http.StatusNotFound,
+		"/debug.log":   http.StatusNotFound,
+		"/docs/":       http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != status {
+			t.Errorf("%s returned status %d, expected %d", path, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package files_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+// Creates a repository with two commits, the first one tagged v1.0.0
+func createGitRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repoDir := t.TempDir()
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repoDir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com", "GIT_COMMITTER_NAME=Alice", "GIT_COMMITTER_EMAIL=alice@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	write := func(name, content string) {
+		if err := os.MkdirAll(filepath.Join(repoDir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	git("init", "-q")
+	write("docs/guide.md", "Guide v1")
+	write("changelog.txt", "1.0.0")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Release 1.0.0")
+	git("tag", "v1.0.0")
+
+	write("docs/guide.md", "Guide v2")
+	write("docs/api.md", "API")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Update docs")
+
+	// Changes in the working copy are not served
+	write("docs/guide.md", "Uncommitted")
+
+	return repoDir
+}
+
+func TestGitFSReadsRefs(t *testing.T) {
+	repo, err := files.OpenGitRepo(createGitRepo(t))
+	if err != nil {
+		t.Fatalf("OpenGitRepo() returned error: %v", err)
+	}
+
+	tests := map[string]string{
+		"HEAD":    "Guide v2",
+		"v1.0.0":  "Guide v1",
+		"HEAD~1":  "Guide v1",
+		"v1.0.0^": "",
+	}
+
+	for ref, expected := range tests {
+		tree, err := repo.Tree(ref)
+		if expected == "" {
+			if !errors.Is(err, files.ErrUnknownRef) {
+				t.Errorf("Tree(%q) returned %v, expected ErrUnknownRef", ref, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Tree(%q) returned error: %v", ref, err)
+		}
+
+		content, err := fs.ReadFile(tree, "docs/guide.md")
+		if err != nil || string(content) != expected {
+			t.Errorf("docs/guide.md at %s is %q (%v), expected %q", ref, content, err, expected)
+		}
+	}
+
+	if _, err := repo.Tree("--output=/tmp/x"); !errors.Is(err, files.ErrUnknownRef) {
+		t.Errorf("Tree() of an option returned %v, expected ErrUnknownRef", err)
+	}
+
+	tree, err := repo.Tree("HEAD")
+	if err != nil {
+		t.Fatalf("Tree() returned error: %v", err)
+	}
+
+	if err := fstest.TestFS(tree, "changelog.txt", "docs/guide.md", "docs/api.md"); err != nil {
+		t.Errorf("GitFS does not behave as a file system: %v", err)
+	}
+
+	refs, err := repo.Refs()
+	if err != nil || len(refs) != 2 || refs[1] != "v1.0.0" {
+		t.Errorf("Refs() returned %v (%v), expected a branch and v1.0.0", refs, err)
+	}
+}
+
+func TestGitFSLastCommits(t *testing.T) {
+	repo, err := files.OpenGitRepo(createGitRepo(t))
+	if err != nil {
+		t.Fatalf("OpenGitRepo() returned error: %v", err)
+	}
+
+	tree, err := repo.Tree("HEAD")
+	if err != nil {
+		t.Fatalf("Tree() returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"changelog.txt": "Release 1.0.0",
+		"docs":          "Update docs",
+	}
+
+	entries, err := files.GetEntries(tree, ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+
+	for _, entry := range entries {
+		commit := entry.Commit()
+		if commit == nil || commit.Subject != expected[entry.Name(false)] || commit.Author != "Alice" {
+			t.Errorf("Last commit of %s is %+v, expected %q by Alice", entry.Name(false), commit, expected[entry.Name(false)])
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Equal(commit.Time) {
+			t.Errorf("Modification time of %s should be the time of its last commit", entry.Name(false))
+		}
+	}
+}
+
+func TestGitFSLastCommitsFromSubdirectory(t *testing.T) {
+	// Opened from a directory of the work tree, paths are still those of the tree
+	repo, err := files.OpenGitRepo(filepath.Join(createGitRepo(t), "docs"))
+	if err != nil {
+		t.Fatalf("OpenGitRepo() returned error: %v", err)
+	}
+
+	tree, err := repo.Tree("HEAD")
+	if err != nil {
+		t.Fatalf("Tree() returned error: %v", err)
+	}
+
+	entries, err := files.GetEntries(tree, "docs")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	for _, entry := range entries {
+		if commit := entry.Commit(); commit == nil || commit.Subject != "Update docs" {
+			t.Errorf("Last commit of docs/%s is %+v, expected \"Update docs\"", entry.Name(false), commit)
+		}
+	}
+}
+
+func TestGitRepoConcurrentTrees(t *testing.T) {
+	repo, err := files.OpenGitRepo(createGitRepo(t))
+	if err != nil {
+		t.Fatalf("OpenGitRepo() returned error: %v", err)
+	}
+
+	trees := make([]*files.GitFS, 8)
+
+	var wg sync.WaitGroup
+	for i := range trees {
+		wg.Go(func() {
+			tree, err := repo.Tree("HEAD")
+			if err != nil {
+				t.Errorf("Tree() returned error: %v", err)
+				return
+			}
+			trees[i] = tree
+
+			entries, err := files.GetEntries(tree, ".")
+			if err != nil || len(entries) == 0 || entries[0].Commit() == nil {
+				t.Errorf("Expected entries with last commits, got %v (%v)", entries, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, tree := range trees[1:] {
+		if tree != trees[0] {
+			t.Errorf("Concurrent requests for a commit should share a single tree")
+			break
+		}
+	}
+}
+
+func TestServerGitRefParameter(t *testing.T) {
+	config := server.ServerConfig{GitDir: createGitRepo(t), GitRef: "v1.0.0"}
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		url     string
+		status  int
+		content string
+	}{
+		{"/docs/guide.md", http.StatusOK, "Guide v1"},
+		{"/docs/guide.md?ref=HEAD", http.StatusOK, "Guide v2"},
+		{"/docs/api.md", http.StatusNotFound, ""},
+		{"/docs/guide.md?ref=v9.9.9", http.StatusNotFound, ""},
+		// Commits which are not a branch or tag cannot be browsed
+		{"/docs/guide.md?ref=HEAD~1", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		resp, err := http.Get(ts.URL + test.url)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", test.url, err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s returned status %d, expected %d", test.url, resp.StatusCode, test.status)
+		}
+		if test.content != "" && string(body) != test.content {
+			t.Errorf("%s returned %q, expected %q", test.url, body, test.content)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/?ref=HEAD")
+	if err != nil {
+		t.Fatalf("Failed to get listing: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "/docs/?ref=HEAD") || !strings.Contains(string(body), "Update docs") {
+		t.Errorf("Listing should link within the ref and show last commits")
+	}
+}
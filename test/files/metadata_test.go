@@ -0,0 +1,192 @@
+package files_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		numBytes int64
+		units    files.SizeUnits
+		expected string
+	}{
+		{999, files.SizeUnitsSI, "999 B"},
+		{1000, files.SizeUnitsSI, "1.0 kB"},
+		{1500000, files.SizeUnitsSI, "1.5 MB"},
+		{1023, files.SizeUnitsBinary, "1023 B"},
+		{1024, files.SizeUnitsBinary, "1.0 KiB"},
+		{1572864, files.SizeUnitsBinary, "1.5 MiB"},
+	}
+
+	for _, test := range tests {
+		if size := files.FormatSize(test.numBytes, test.units); size != test.expected {
+			t.Errorf("FormatSize(%d, %s) = %q, expected %q", test.numBytes, test.units, size, test.expected)
+		}
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	tests := []struct {
+		since    time.Duration
+		expected string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{3 * time.Hour, "3 hours ago"},
+		{49 * time.Hour, "2 days ago"},
+		{90 * 24 * time.Hour, "3 months ago"},
+		{800 * 24 * time.Hour, "2 years ago"},
+		{-5 * time.Minute, "in 5 minutes"},
+	}
+
+	for _, test := range tests {
+		if relative := files.FormatRelativeTime(test.since); relative != test.expected {
+			t.Errorf("FormatRelativeTime(%s) = %q, expected %q", test.since, relative, test.expected)
+		}
+	}
+}
+
+var metadataTestFS = fstest.MapFS{
+	"report.pdf":    {Data: make([]byte, 1234567), ModTime: time.Now().Add(-2 * time.Hour)},
+	"notes":         {Data: []byte("x")},
+	"docs/a.md":     {Data: []byte("a")},
+	"docs/b.md":     {Data: []byte("b")},
+	"docs/sub/c.md": {Data: []byte("c")},
+	"empty":         {Mode: os.ModeDir},
+}
+
+func TestCountItemsLimit(t *testing.T) {
+	fsys := fstest.MapFS{"exact": {Mode: os.ModeDir}}
+	for i := range files.MaxCountedItems + 5 {
+		fsys[fmt.Sprintf("huge/%d.txt", i)] = &fstest.MapFile{}
+	}
+	for i := range files.MaxCountedItems {
+		fsys[fmt.Sprintf("exact/%d.txt", i)] = &fstest.MapFile{}
+	}
+
+	entries, err := files.GetEntries(fsys, ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	files.CountItems(fsys, ".", entries)
+
+	expected := map[string]string{"exact": "1000 items", "huge": "1000+ items"}
+	for _, entry := range entries {
+		if items := entry.Items(); items != expected[entry.Name(false)] {
+			t.Errorf("Items() of %s = %q, expected %q", entry.Name(false), items, expected[entry.Name(false)])
+		}
+		if count := entry.ItemCount(); count != files.MaxCountedItems {
+			t.Errorf("ItemCount() of %s = %d, expected %d", entry.Name(false), count, files.MaxCountedItems)
+		}
+	}
+}
+
+func TestDirEntryMetadata(t *testing.T) {
+	entries, err := files.GetEntries(metadataTestFS, ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	files.CountItems(metadataTestFS, ".", entries)
+
+	byName := make(map[string]files.DirEntry)
+	for _, entry := range entries {
+		byName[entry.Name(false)] = entry
+	}
+
+	report := byName["report.pdf"]
+	if size := report.ExactSize(); size != "1,234,567 bytes" {
+		t.Errorf("ExactSize() = %q, expected \"1,234,567 bytes\"", size)
+	}
+	if size := report.SizeIn(files.SizeUnitsBinary); size != "1.2 MiB" {
+		t.Errorf("SizeIn(binary) = %q, expected \"1.2 MiB\"", size)
+	}
+	if mimeType := report.MIMEType(); mimeType != "application/pdf" {
+		t.Errorf("MIMEType() = %q, expected \"application/pdf\"", mimeType)
+	}
+	if relative := report.ModTimeRelative(); relative != "2 hours ago" {
+		t.Errorf("ModTimeRelative() = %q, expected \"2 hours ago\"", relative)
+	}
+	if report.ItemCount() != -1 || report.Items() != "" {
+		t.Errorf("Files should not have item counts")
+	}
+
+	if size := byName["notes"].ExactSize(); size != "1 byte" {
+		t.Errorf("ExactSize() = %q, expected \"1 byte\"", size)
+	}
+	if mimeType := byName["notes"].MIMEType(); mimeType != "" {
+		t.Errorf("MIMEType() of a file without extension = %q, expected none", mimeType)
+	}
+
+	if items := byName["docs"].Items(); items != "3 items" {
+		t.Errorf("Items() = %q, expected \"3 items\"", items)
+	}
+	if count := byName["empty"].ItemCount(); count != 0 {
+		t.Errorf("ItemCount() of an empty directory = %d, expected 0", count)
+	}
+	if byName["docs"].ModTimeRelative() != "" || byName["docs"].ModTimeString() != "" {
+		t.Errorf("Unknown modification times should be empty")
+	}
+}
+
+func TestDirEntryOwner(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := files.GetEntries(files.NewOSFS(dir), ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	hasOwner := entries[0].Owner() != "" && entries[0].Group() != ""
+	if hasOwner != (runtime.GOOS != "windows") {
+		t.Errorf("Owner() = %q, Group() = %q on %s", entries[0].Owner(), entries[0].Group(), runtime.GOOS)
+	}
+
+	// Files in memory have no owners
+	entries, _ = files.GetEntries(metadataTestFS, ".")
+	if files.HasOwners(entries) {
+		t.Errorf("HasOwners() should be false without owners")
+	}
+}
+
+func TestServerListingMetadata(t *testing.T) {
+	for _, theme := range []string{"basic", "pretty"} {
+		config := server.ServerConfig{FS: metadataTestFS, DirViewTheme: theme, SizeUnits: files.SizeUnitsBinary}
+
+		mux, err := config.NewServeMux()
+		if err != nil {
+			t.Fatalf("NewServeMux() returned error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		body := rec.Body.String()
+		for _, expected := range []string{"1.2 MiB", `title="1,234,567 bytes"`, "application/pdf", "2 hours ago", "3 items"} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("%s theme listing should contain %q", theme, expected)
+			}
+		}
+	}
+
+	config := server.ServerConfig{FS: metadataTestFS, SizeUnits: "kibi"}
+	if _, err := config.NewServeMux(); err == nil {
+		t.Errorf("NewServeMux() should reject unknown size units")
+	}
+}
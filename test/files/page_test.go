@@ -0,0 +1,197 @@
+package files_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/tmpl/dirview/themes"
+)
+
+// Files f00.txt to f24.txt, and the "sub" directory
+func pageTestFS() fstest.MapFS {
+	fsys := fstest.MapFS{"sub/x": {Data: []byte("x")}}
+	for i := range 25 {
+		fsys[fmt.Sprintf("f%02d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+
+	return fsys
+}
+
+func entryNames(entries []files.DirEntry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name(false))
+	}
+
+	return names
+}
+
+func TestListOptionsPaginate(t *testing.T) {
+	entries, err := files.GetEntries(pageTestFS(), ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	query, _ := url.ParseQuery("page=3&per_page=10")
+	opts, err := files.ParseListOptions(query)
+	if err != nil {
+		t.Fatalf("ParseListOptions() returned error: %v", err)
+	}
+
+	pageEntries, page := opts.Paginate(opts.Apply(entries))
+	if names := entryNames(pageEntries); !slices.Equal(names, []string{"f19.txt", "f20.txt", "f21.txt", "f22.txt", "f23.txt", "f24.txt"}) {
+		t.Errorf("Page 3 listed %v", names)
+	}
+	if page.Number != 3 || page.HasNext {
+		t.Errorf("Page 3 = %+v, expected the last page", page)
+	}
+
+	query, _ = url.ParseQuery("per_page=10")
+	opts, _ = files.ParseListOptions(query)
+	pageEntries, page = opts.Paginate(opts.Apply(entries))
+	if len(pageEntries) != 10 || pageEntries[0].Name(false) != "sub" || !page.HasNext {
+		t.Fatalf("First page listed %v with %+v", entryNames(pageEntries), page)
+	}
+
+	// The cursor continues after the last entry of the page
+	query.Set("cursor", page.NextCursor)
+	opts, _ = files.ParseListOptions(query)
+	pageEntries, page = opts.Paginate(opts.Apply(entries))
+	if pageEntries[0].Name(false) != "f09.txt" || page.Number != 0 || !page.HasNext {
+		t.Errorf("Page after the cursor listed %v with %+v", entryNames(pageEntries), page)
+	}
+
+	// Huge page numbers are past the end
+	query, _ = url.ParseQuery(fmt.Sprintf("page=%d&per_page=10000", 1<<62))
+	opts, _ = files.ParseListOptions(query)
+	if pageEntries, _ = opts.Paginate(entries); len(pageEntries) != 0 {
+		t.Errorf("Page past the end listed %v", entryNames(pageEntries))
+	}
+}
+
+func TestParseListOptionsInvalidPage(t *testing.T) {
+	for _, query := range []string{"page=0", "page=abc", "per_page=-1", "per_page=10001", "cursor=!!", "page=2&cursor=Zg"} {
+		values, _ := url.ParseQuery(query)
+
+		if _, err := files.ParseListOptions(values); !errors.Is(err, files.ErrInvalidListOptions) {
+			t.Errorf("ParseListOptions(%q) returned %v, expected ErrInvalidListOptions", query, err)
+		}
+	}
+}
+
+func TestEntryStream(t *testing.T) {
+	fsys := pageTestFS()
+
+	readAll := func(query string) ([]string, files.Page) {
+		values, _ := url.ParseQuery(query)
+		opts, err := files.ParseListOptions(values)
+		if err != nil {
+			t.Fatalf("ParseListOptions(%q) returned error: %v", query, err)
+		}
+
+		stream, err := files.StreamEntriesFS(fsys, ".", opts)
+		if err != nil {
+			t.Fatalf("StreamEntriesFS() returned error: %v", err)
+		}
+		defer stream.Close()
+
+		var names []string
+		for {
+			entries, err := stream.Next()
+			if err != nil {
+				t.Fatalf("Next() returned error: %v", err)
+			}
+			if len(entries) == 0 {
+				return names, stream.Page
+			}
+			names = append(names, entryNames(entries)...)
+		}
+	}
+
+	// Directories are not listed first without sorting
+	names, page := readAll("sort=none")
+	if len(names) != 26 || names[25] != "sub" || page.HasNext {
+		t.Errorf("Unpaginated stream listed %v with %+v", names, page)
+	}
+
+	names, page = readAll("sort=none&per_page=4&page=2&filter=f1*")
+	if !slices.Equal(names, []string{"f14.txt", "f15.txt", "f16.txt", "f17.txt"}) || !page.HasNext {
+		t.Fatalf("Filtered page 2 listed %v with %+v", names, page)
+	}
+
+	names, page = readAll("sort=none&per_page=4&filter=f1*&cursor=" + page.NextCursor)
+	if !slices.Equal(names, []string{"f18.txt", "f19.txt"}) || page.HasNext {
+		t.Errorf("Page after the cursor listed %v with %+v", names, page)
+	}
+}
+
+func TestServerPaginatedListing(t *testing.T) {
+	for _, theme := range []string{"basic", "pretty"} {
+		config := server.ServerConfig{FS: pageTestFS(), DirViewTheme: theme, PageSize: 10}
+
+		mux, err := config.NewServeMux()
+		if err != nil {
+			t.Fatalf("NewServeMux() returned error: %v", err)
+		}
+
+		for _, query := range []string{"page=2", "page=2&sort=none"} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+
+			body := rec.Body.String()
+			if strings.Contains(body, "f08.txt") || !strings.Contains(body, "f10.txt") || strings.Contains(body, "f20.txt") {
+				t.Errorf("%s theme with ?%s should list the second page", theme, query)
+			}
+
+			for _, link := range []string{"page=1", "page=3"} {
+				if !strings.Contains(body, link) {
+					t.Errorf("%s theme with ?%s should link to %s", theme, query, link)
+				}
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?page=0", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Invalid page returned status %d, expected 400", rec.Code)
+		}
+	}
+}
+
+func TestServerPrettyWindowedListing(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := range themes.FlushRows + 1 {
+		fsys[fmt.Sprintf("f%05d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+
+	config := server.ServerConfig{FS: fsys, DirViewTheme: "pretty"}
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	for _, query := range []string{"", "?sort=none"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+
+		// Every row is sent, in chunks the page keeps only near the view
+		body := rec.Body.String()
+		if !strings.Contains(body, fmt.Sprintf("f%05d.txt", themes.FlushRows)) {
+			t.Errorf("Listing with %q should list every entry", query)
+		}
+		if chunks := strings.Count(body, "data-rows"); chunks != 3 {
+			t.Errorf("Listing with %q has %d mentions of data-rows, expected 2 chunks and the window script", query, chunks)
+		}
+	}
+}
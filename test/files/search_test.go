@@ -0,0 +1,189 @@
+package files_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+var searchTestFS = fstest.MapFS{
+	"README.md":              {Data: []byte("readme")},
+	"docs/guide.md":          {Data: []byte("guide")},
+	"docs/api/readme.txt":    {Data: []byte("api")},
+	"docs/api/v1/old.md":     {Data: []byte("old")},
+	"secrets/readme.md":      {Data: []byte("secret")},
+	"src/main.go":            {Data: []byte("package main")},
+	"src/readme_parser.go":   {Data: []byte("package main")},
+	"src/readme_parser.swp":  {Data: []byte("swap")},
+	"src/vendor/lib/util.go": {Data: []byte("package lib")},
+}
+
+func searchPaths(search *files.Search) []string {
+	var paths []string
+	for result := range search.Results(context.Background()) {
+		paths = append(paths, result.Path)
+	}
+
+	return paths
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		query    string
+		dir      string
+		opts     files.SearchOptions
+		expected []string
+		stopped  files.SearchStop
+	}{
+		{"readme", ".", files.SearchOptions{}, []string{"README.md", "docs/api/readme.txt", "secrets/readme.md", "src/readme_parser.go", "src/readme_parser.swp"}, ""},
+		{"*.MD", ".", files.SearchOptions{}, []string{"README.md", "docs/api/v1/old.md", "docs/guide.md", "secrets/readme.md"}, ""},
+		{"*.md", "docs", files.SearchOptions{}, []string{"api/v1/old.md", "guide.md"}, ""},
+		{"*.md", ".", files.SearchOptions{MaxDepth: 2}, []string{"README.md", "docs/guide.md", "secrets/readme.md"}, files.SearchStopDepth},
+		{"*.go", ".", files.SearchOptions{MaxResults: 2}, []string{"src/main.go", "src/readme_parser.go"}, files.SearchStopResults},
+		{"api", ".", files.SearchOptions{}, []string{"docs/api"}, ""},
+	}
+
+	for _, test := range tests {
+		search, err := files.NewSearch(searchTestFS, test.dir, test.query, test.opts)
+		if err != nil {
+			t.Fatalf("NewSearch(%q) returned error: %v", test.query, err)
+		}
+
+		if paths := searchPaths(search); !slices.Equal(paths, test.expected) {
+			t.Errorf("Search %q in %s found %v, expected %v", test.query, test.dir, paths, test.expected)
+		}
+
+		if search.Count != len(test.expected) || search.Stopped != test.stopped {
+			t.Errorf("Search %q counted %d and stopped for %q, expected %d and %q", test.query, search.Count, search.Stopped, len(test.expected), test.stopped)
+		}
+	}
+}
+
+func TestSearchInvalidQuery(t *testing.T) {
+	for _, query := range []string{"", "  ", "[a-"} {
+		if _, err := files.NewSearch(searchTestFS, ".", query, files.SearchOptions{}); !errors.Is(err, files.ErrInvalidSearch) {
+			t.Errorf("NewSearch(%q) returned %v, expected ErrInvalidSearch", query, err)
+		}
+	}
+}
+
+func TestSearchStopsWhenCancelled(t *testing.T) {
+	search, _ := files.NewSearch(searchTestFS, ".", "*", files.SearchOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for result := range search.Results(ctx) {
+		t.Errorf("Cancelled search found %s", result.Path)
+	}
+}
+
+func TestSearchSkipsHiddenPaths(t *testing.T) {
+	filter, err := files.NewFilter(files.FilterOptions{Exclude: []string{"/secrets/", "*.swp"}})
+	if err != nil {
+		t.Fatalf("NewFilter() returned error: %v", err)
+	}
+
+	search, _ := files.NewSearch(filter.FS(searchTestFS), ".", "readme", files.SearchOptions{})
+
+	expected := []string{"README.md", "docs/api/readme.txt", "src/readme_parser.go"}
+	if paths := searchPaths(search); !slices.Equal(paths, expected) {
+		t.Errorf("Search found %v, expected %v", paths, expected)
+	}
+}
+
+func TestSearchDoesNotFollowSymlinkedDirs(t *testing.T) {
+	rootDir, _ := createSymlinkTestDirs(t)
+	if err := os.Symlink("..", filepath.Join(rootDir, "sub", "loop")); err != nil {
+		t.Skipf("Cannot create symlinks: %v", err)
+	}
+
+	search, _ := files.NewSearch(files.NewOSFS(rootDir), ".", "*", files.SearchOptions{})
+
+	paths := searchPaths(search)
+	expected := []string{"file.txt", "file_lnk", "shared_lnk", "sub", "sub/loop", "sub_lnk"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("Search found %v, expected %v", paths, expected)
+	}
+}
+
+func TestServerSearch(t *testing.T) {
+	for _, theme := range []string{"basic", "pretty"} {
+		config := server.ServerConfig{FS: searchTestFS, DirViewTheme: theme}
+
+		mux, err := config.NewServeMux()
+		if err != nil {
+			t.Fatalf("NewServeMux() returned error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/?search=*.md", nil))
+
+		body := rec.Body.String()
+		if !strings.Contains(body, `href="/docs/api/v1/old.md"`) || strings.Contains(body, "README.md") {
+			t.Errorf("%s theme should list matches under the searched directory", theme)
+		}
+		if !strings.Contains(body, "2 results") {
+			t.Errorf("%s theme should summarise the results", theme)
+		}
+
+		// Listings have a search box
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if !strings.Contains(rec.Body.String(), `name="search"`) {
+			t.Errorf("%s theme listing should have a search box", theme)
+		}
+	}
+
+	config := server.ServerConfig{FS: searchTestFS}
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?search=main", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("JSON search returned %d lines, expected a match and a summary: %s", len(lines), rec.Body.String())
+	}
+
+	var result struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &result); err != nil || result.Path != "/src/main.go" || result.Size != 12 {
+		t.Errorf("JSON search returned %s, expected /src/main.go", lines[0])
+	}
+
+	var summary struct {
+		Done  bool `json:"done"`
+		Count int  `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &summary); err != nil || !summary.Done || summary.Count != 1 {
+		t.Errorf("JSON search ended with %s, expected a summary", lines[1])
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?search=[a-", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Invalid search returned status %d, expected 400", rec.Code)
+	}
+}
@@ -19,7 +19,10 @@ func createTestServer(t *testing.T, rootDir string) *httptest.Server {
 	}
 
 	// Use the existing NewServeMux function instead of manually creating mux
-	mux := config.NewServeMux()
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
 
 	ts := httptest.NewServer(mux)
 	t.Cleanup(func() {
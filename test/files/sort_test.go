@@ -0,0 +1,152 @@
+package files_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+func TestCompareNatural(t *testing.T) {
+	ordered := []string{"file1.txt", "File2.txt", "file10.txt", "v1.9.0", "v1.10.0", "v01.11.0", "v2"}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		if files.CompareNatural(ordered[i], ordered[i+1]) >= 0 {
+			t.Errorf("%q should sort before %q", ordered[i], ordered[i+1])
+		}
+		if files.CompareNatural(ordered[i+1], ordered[i]) <= 0 {
+			t.Errorf("%q should sort after %q", ordered[i+1], ordered[i])
+		}
+	}
+
+	if files.CompareNatural("same", "same") != 0 {
+		t.Errorf("Equal names should compare equal")
+	}
+}
+
+var sortTestFS = fstest.MapFS{
+	"b10.log":   {Data: make([]byte, 30), ModTime: time.Unix(300, 0)},
+	"b2.txt":    {Data: make([]byte, 10), ModTime: time.Unix(100, 0)},
+	"a.md":      {Data: make([]byte, 20), ModTime: time.Unix(200, 0)},
+	"Logs/x":    {Data: []byte("x"), ModTime: time.Unix(50, 0)},
+	"docs/y":    {Data: []byte("y"), ModTime: time.Unix(400, 0)},
+	"cache.log": {Data: make([]byte, 5), ModTime: time.Unix(500, 0)},
+}
+
+func TestListOptionsApply(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"docs", "Logs", "a.md", "b2.txt", "b10.log", "cache.log"}},
+		{"sort=name&order=desc", []string{"Logs", "docs", "cache.log", "b10.log", "b2.txt", "a.md"}},
+		{"sort=size", []string{"docs", "Logs", "cache.log", "b2.txt", "a.md", "b10.log"}},
+		{"sort=mtime&order=desc", []string{"Logs", "docs", "cache.log", "b10.log", "a.md", "b2.txt"}},
+		{"sort=type", []string{"docs", "Logs", "b10.log", "cache.log", "a.md", "b2.txt"}},
+		{"filter=*.LOG", []string{"b10.log", "cache.log"}},
+		{"filter=*o*&sort=size", []string{"docs", "Logs", "cache.log", "b10.log"}},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+
+		opts, err := files.ParseListOptions(query)
+		if err != nil {
+			t.Fatalf("ParseListOptions(%q) returned error: %v", test.query, err)
+		}
+
+		entries, err := files.GetEntries(sortTestFS, ".")
+		if err != nil {
+			t.Fatalf("GetEntries() returned error: %v", err)
+		}
+
+		var names []string
+		for _, entry := range opts.Apply(entries) {
+			names = append(names, entry.Name(false))
+		}
+
+		if !slices.Equal(names, test.expected) {
+			t.Errorf("?%s listed %v, expected %v", test.query, names, test.expected)
+		}
+	}
+}
+
+// Directory entry counting the calls to Info
+type countingEntry struct {
+	fs.DirEntry
+	calls *int
+}
+
+func (e countingEntry) Info() (fs.FileInfo, error) {
+	*e.calls++
+	return e.DirEntry.Info()
+}
+
+func TestListOptionsApplyReadsInfoOnce(t *testing.T) {
+	fsEntries, err := fs.ReadDir(sortTestFS, ".")
+	if err != nil {
+		t.Fatalf("ReadDir() returned error: %v", err)
+	}
+
+	calls := 0
+	entries := make([]files.DirEntry, 0, len(fsEntries))
+	for _, entry := range fsEntries {
+		entries = append(entries, files.DirEntry{DirEntry: countingEntry{DirEntry: entry, calls: &calls}})
+	}
+
+	files.ListOptions{Sort: files.SortSize}.Apply(entries)
+
+	if calls != len(entries) {
+		t.Errorf("Sorting %d entries called Info() %d times, expected once per entry", len(entries), calls)
+	}
+}
+
+func TestParseListOptionsInvalid(t *testing.T) {
+	for _, query := range []string{"sort=owner", "order=up", "filter=[a-"} {
+		values, _ := url.ParseQuery(query)
+
+		if _, err := files.ParseListOptions(values); !errors.Is(err, files.ErrInvalidListOptions) {
+			t.Errorf("ParseListOptions(%q) returned %v, expected ErrInvalidListOptions", query, err)
+		}
+	}
+}
+
+func TestServerSortedListing(t *testing.T) {
+	for _, theme := range []string{"basic", "pretty"} {
+		config := server.ServerConfig{FS: sortTestFS, DirViewTheme: theme}
+
+		mux, err := config.NewServeMux()
+		if err != nil {
+			t.Fatalf("NewServeMux() returned error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?sort=size&order=desc", nil))
+
+		body := rec.Body.String()
+		if strings.Index(body, "b10.log") > strings.Index(body, "cache.log") {
+			t.Errorf("%s theme should list larger files first", theme)
+		}
+
+		// The header of the current sort key reverses the order
+		if !strings.Contains(body, `href="?sort=size"`) || !strings.Contains(body, `href="?sort=name"`) {
+			t.Errorf("%s theme should link column headers to sorted listings", theme)
+		}
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?sort=owner", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Unknown sort key returned status %d, expected 400", rec.Code)
+		}
+	}
+}
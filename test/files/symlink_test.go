@@ -0,0 +1,177 @@
+package files_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ducng99/goserve/internal/files"
+)
+
+// Creates a root with symlinks to a file and a directory inside it, and to a directory outside it
+func createSymlinkTestDirs(t *testing.T) (rootDir, sharedDir string) {
+	baseDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+
+	rootDir = filepath.Join(baseDir, "root")
+	sharedDir = filepath.Join(baseDir, "shared")
+
+	for _, dir := range []string{filepath.Join(rootDir, "sub"), sharedDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(rootDir, "file.txt"), []byte("file"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sharedDir, "shared.txt"), []byte("shared"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	links := map[string]string{
+		"file_lnk":   "file.txt",
+		"sub_lnk":    "sub",
+		"shared_lnk": "../shared",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(rootDir, name)); err != nil {
+			t.Skipf("Cannot create symlinks: %v", err)
+		}
+	}
+
+	return rootDir, sharedDir
+}
+
+func TestOSFSSymlinkPolicies(t *testing.T) {
+	rootDir, sharedDir := createSymlinkTestDirs(t)
+
+	// Symlinks leading to the root itself are not treated by the policy, e.g. /var to /private/var
+	linkedRootDir := filepath.Join(filepath.Dir(rootDir), "root_lnk")
+	if err := os.Symlink(rootDir, linkedRootDir); err != nil {
+		t.Skipf("Cannot create symlinks: %v", err)
+	}
+
+	tests := []struct {
+		policy    files.SymlinkPolicy
+		allowDirs []string
+		// Error opening each path, nil if it can be read
+		open map[string]error
+		// Whether each symlink is listed, and followable
+		listed map[string]bool
+	}{
+		{
+			policy: files.SymlinksNever,
+			open:   map[string]error{"file.txt": nil, "file_lnk": fs.ErrNotExist, "sub_lnk": fs.ErrNotExist, "shared_lnk/shared.txt": fs.ErrNotExist},
+			listed: map[string]bool{},
+		},
+		{
+			policy: files.SymlinksWithinRoot,
+			open:   map[string]error{"file.txt": nil, "file_lnk": nil, "sub_lnk": nil, "shared_lnk/shared.txt": fs.ErrPermission},
+			listed: map[string]bool{"file_lnk": true, "sub_lnk": true, "shared_lnk": false},
+		},
+		{
+			policy:    files.SymlinksAllowList,
+			allowDirs: []string{sharedDir},
+			open:      map[string]error{"file_lnk": nil, "shared_lnk/shared.txt": nil},
+			listed:    map[string]bool{"file_lnk": true, "sub_lnk": true, "shared_lnk": true},
+		},
+		{
+			policy: files.SymlinksShow,
+			open:   map[string]error{"file.txt": nil, "file_lnk": fs.ErrPermission, "shared_lnk/shared.txt": fs.ErrPermission},
+			listed: map[string]bool{"file_lnk": false, "sub_lnk": false, "shared_lnk": false},
+		},
+	}
+
+	for _, test := range tests {
+		for _, root := range []string{rootDir, linkedRootDir} {
+			t.Run(string(test.policy)+"/"+filepath.Base(root), func(t *testing.T) {
+				osfs := files.NewOSFS(root).WithSymlinks(test.policy, test.allowDirs...)
+
+				for name, expected := range test.open {
+					_, err := fs.Stat(osfs, name)
+					if (expected == nil && err != nil) || (expected != nil && !errors.Is(err, expected)) {
+						t.Errorf("Stat(%q) returned %v, expected %v", name, err, expected)
+					}
+				}
+
+				entries, err := files.GetEntries(osfs, ".")
+				if err != nil {
+					t.Fatalf("GetEntries() returned error: %v", err)
+				}
+
+				links := 0
+				for _, entry := range entries {
+					if entry.LinkTarget() == "" {
+						continue
+					}
+					links++
+
+					followable, ok := test.listed[entry.Name(false)]
+					if !ok {
+						t.Errorf("Symlink %s should not be listed", entry.Name(false))
+						continue
+					}
+
+					if entry.Followable() != followable {
+						t.Errorf("Followable() of %s returned %v, expected %v", entry.Name(false), entry.Followable(), followable)
+					}
+				}
+
+				if links != len(test.listed) {
+					t.Errorf("Expected %d symlinks listed, got %d", len(test.listed), links)
+				}
+			})
+		}
+	}
+}
+
+func TestOSFSSymlinkEntries(t *testing.T) {
+	rootDir, _ := createSymlinkTestDirs(t)
+
+	entries, err := files.GetEntries(files.NewOSFS(rootDir), ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	for _, entry := range entries {
+		switch entry.Name(false) {
+		case "sub_lnk":
+			if entry.LinkTarget() != "sub" || !entry.IsDir() || entry.Name(true) != "sub_lnk/" {
+				t.Errorf("Symlink to a directory should be listed as a directory with its target, got %q", entry.LinkTarget())
+			}
+		case "shared_lnk":
+			if entry.LinkTarget() != "../shared" || entry.IsDir() {
+				t.Errorf("Symlink out of the root should be listed with its target only, got %q", entry.LinkTarget())
+			}
+		case "file.txt":
+			if entry.LinkTarget() != "" || !entry.Followable() {
+				t.Errorf("Regular files should not have a link target")
+			}
+		}
+	}
+}
+
+func TestSanitisePathSiblingPrefix(t *testing.T) {
+	baseDir := t.TempDir()
+	rootDir := filepath.Join(baseDir, "root")
+	siblingDir := filepath.Join(baseDir, "root-other")
+
+	for _, dir := range []string{rootDir, siblingDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	if err := os.Symlink(siblingDir, filepath.Join(rootDir, "other")); err != nil {
+		t.Skipf("Cannot create symlinks: %v", err)
+	}
+
+	if _, err := files.SanitisePath(rootDir, "other"); !errors.Is(err, files.ErrorSanitiseUnauthorized) {
+		t.Errorf("A directory sharing the root's name as a prefix should be outside the root, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package files_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/files"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+func newTestUnionFS() *files.UnionFS {
+	upper := fstest.MapFS{
+		"index.html":     {Data: []byte("upper index")},
+		"assets/app.js":  {Data: []byte("upper app")},
+		"shadowed":       {Data: []byte("upper file")},
+		"upper-only.txt": {Data: []byte("upper only")},
+	}
+
+	lower := fstest.MapFS{
+		"index.html":       {Data: []byte("lower index")},
+		"assets/style.css": {Data: []byte("lower style")},
+		"shadowed/x.txt":   {Data: []byte("lower dir")},
+		"lower-only.txt":   {Data: []byte("lower only")},
+	}
+
+	return files.NewUnionFS(files.Layer{Name: "upper", FS: upper}, files.Layer{Name: "lower", FS: lower})
+}
+
+func TestUnionFSFirstMatchWins(t *testing.T) {
+	union := newTestUnionFS()
+
+	tests := map[string]string{
+		"index.html":       "upper index",
+		"assets/app.js":    "upper app",
+		"assets/style.css": "lower style",
+		"lower-only.txt":   "lower only",
+		"shadowed":         "upper file",
+	}
+
+	for name, expected := range tests {
+		content, err := fs.ReadFile(union, name)
+		if err != nil || string(content) != expected {
+			t.Errorf("ReadFile(%q) returned %q (%v), expected %q", name, content, err, expected)
+		}
+	}
+
+	if _, err := fs.Stat(union, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a missing file returned %v, expected ErrNotExist", err)
+	}
+}
+
+func TestUnionFSMergedListing(t *testing.T) {
+	entries, err := files.GetEntries(newTestUnionFS(), ".")
+	if err != nil {
+		t.Fatalf("GetEntries() returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"assets":         "upper",
+		"index.html":     "upper",
+		"lower-only.txt": "lower",
+		"shadowed":       "upper",
+		"upper-only.txt": "upper",
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+
+	for _, entry := range entries {
+		if layer := expected[entry.Name(false)]; entry.Layer() != layer {
+			t.Errorf("Entry %s is from layer %q, expected %q", entry.Name(false), entry.Layer(), layer)
+		}
+
+		if entry.Name(false) == "shadowed" && entry.IsDir() {
+			t.Errorf("Directory in lower layer should be hidden by the file in upper layer")
+		}
+	}
+
+	if !files.HasLayers(entries) {
+		t.Errorf("HasLayers() should be true for entries of a union")
+	}
+}
+
+func TestUnionFSPerLayerContainment(t *testing.T) {
+	lower := fstest.MapFS{FileSymlinkInaccessible: {Data: []byte("lower")}}
+	union := files.NewUnionFS(files.Layer{Name: "root", FS: files.NewOSFS(RootDir)}, files.Layer{Name: "lower", FS: lower})
+
+	// The symlink leaving the upper root is refused, not looked up in lower layers
+	if _, err := fs.ReadFile(union, FileSymlinkInaccessible); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("ReadFile() of a symlink leaving its layer returned %v, expected ErrPermission", err)
+	}
+}
+
+func TestServerLayerNamesUnique(t *testing.T) {
+	dir := t.TempDir()
+	for _, root := range []string{"site/public", "shared/public", "extra"} {
+		if err := os.MkdirAll(filepath.Join(dir, root), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, root, strings.ReplaceAll(root, "/", "-")+".txt"), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := server.ServerConfig{
+		RootDir:      filepath.Join(dir, "site", "public"),
+		OverlayDirs:  []string{filepath.Join(dir, "shared", "public"), filepath.Join(dir, "extra")},
+		DirViewTheme: "basic",
+	}
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body := rec.Body.String()
+	for _, name := range []string{">site/public<", ">shared/public<", ">extra<"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("Listing should label a layer %s", name)
+		}
+	}
+}
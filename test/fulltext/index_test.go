@@ -0,0 +1,238 @@
+package fulltext_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ducng99/goserve/internal/fulltext"
+	"github.com/ducng99/goserve/pkg/goserve"
+)
+
+func newTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md":          {Data: []byte("# Project\n\nSee the guide for setup.\n")},
+		"docs/guide.md":      {Data: []byte("one\ntwo\nRun the Setup script\nfour\nfive\nsix\n")},
+		"docs/api/notes.txt": {Data: []byte("no matches here\n")},
+		"logs/app.log":       {Data: []byte("setup done\nsetup failed\n")},
+		"bin/tool":           {Data: []byte("setup\x00\x01\x02")},
+		"big.txt":            {Data: []byte(strings.Repeat("setup ", 100))},
+	}
+}
+
+func refresh(t *testing.T, index *fulltext.Index) {
+	t.Helper()
+
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() returned error: %v", err)
+	}
+}
+
+func matchedFiles(results fulltext.Results) []string {
+	var paths []string
+	for _, file := range results.Files {
+		paths = append(paths, file.Path)
+	}
+
+	return paths
+}
+
+func TestIndexSearch(t *testing.T) {
+	fsys := newTestFS()
+	index := fulltext.New(fsys, fulltext.Options{MaxFileSize: 100})
+	refresh(t, index)
+
+	if index.Files() != 4 {
+		t.Errorf("Indexed %d files, expected 4 without the binary and large ones", index.Files())
+	}
+
+	results, err := index.Search(fsys, ".", "SETUP", fulltext.SearchOptions{Context: 2})
+	if err != nil {
+		t.Fatalf("Search() returned error: %v", err)
+	}
+
+	expected := []string{"README.md", "docs/guide.md", "logs/app.log"}
+	if paths := matchedFiles(results); !slices.Equal(paths, expected) {
+		t.Errorf("Search matched %v, expected %v", paths, expected)
+	}
+	if results.Count != 4 || results.Truncated {
+		t.Errorf("Search counted %d lines, truncated %v, expected 4 and false", results.Count, results.Truncated)
+	}
+
+	match := results.Files[1].Matches[0]
+	if match.Line != 3 || match.Text != "Run the Setup script" || !slices.Equal(match.Before, []string{"one", "two"}) || !slices.Equal(match.After, []string{"four", "five"}) {
+		t.Errorf("Search matched %+v, expected line 3 with two lines of context", match)
+	}
+
+	results, _ = index.Search(fsys, "docs", "setup", fulltext.SearchOptions{})
+	if paths := matchedFiles(results); !slices.Equal(paths, []string{"docs/guide.md"}) {
+		t.Errorf("Search of docs matched %v, expected docs/guide.md", paths)
+	}
+
+	results, _ = index.Search(fsys, ".", "setup", fulltext.SearchOptions{MaxMatches: 2})
+	if results.Count != 2 || !results.Truncated {
+		t.Errorf("Limited search counted %d lines, truncated %v, expected 2 and true", results.Count, results.Truncated)
+	}
+}
+
+func TestIndexRefreshUpdatesChangedFiles(t *testing.T) {
+	fsys := newTestFS()
+	index := fulltext.New(fsys, fulltext.Options{})
+	refresh(t, index)
+
+	fsys["docs/guide.md"] = &fstest.MapFile{Data: []byte("rewritten\n"), ModTime: time.Now()}
+	fsys["docs/new.md"] = &fstest.MapFile{Data: []byte("a new setup page\n")}
+	delete(fsys, "logs/app.log")
+	refresh(t, index)
+
+	results, _ := index.Search(fsys, ".", "setup", fulltext.SearchOptions{})
+	expected := []string{"README.md", "big.txt", "docs/new.md"}
+	if paths := matchedFiles(results); !slices.Equal(paths, expected) {
+		t.Errorf("Search after changes matched %v, expected %v", paths, expected)
+	}
+
+	// Files hidden before the next scan are left out
+	delete(fsys, "big.txt")
+	results, _ = index.Search(fsys, ".", "setup", fulltext.SearchOptions{})
+	if paths := matchedFiles(results); slices.Contains(paths, "big.txt") {
+		t.Errorf("Search matched %v, expected removed big.txt to be left out", paths)
+	}
+}
+
+// Waits for a search of the index to match the expected files
+func waitForMatches(t *testing.T, index *fulltext.Index, fsys fs.FS, query string, expected []string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		results, _ := index.Search(fsys, ".", query, fulltext.SearchOptions{})
+		paths := matchedFiles(results)
+		if slices.Equal(paths, expected) {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Search matched %v, expected %v", paths, expected)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestIndexRunWatchesDirs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "first.md"), []byte("watch setup\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := os.DirFS(dir)
+	// Changes are only seen through events, as the interval is never reached
+	index := fulltext.New(fsys, fulltext.Options{Interval: time.Hour, Dirs: []string{dir}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go index.Run(ctx)
+
+	waitForMatches(t, index, fsys, "setup", []string{"first.md"})
+
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "new.md"), []byte("new setup\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForMatches(t, index, fsys, "setup", []string{"docs/new.md", "first.md"})
+
+	// Files added to a directory found through events are watched too
+	if err := os.WriteFile(filepath.Join(dir, "docs", "later.md"), []byte("later setup\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForMatches(t, index, fsys, "setup", []string{"docs/later.md", "docs/new.md", "first.md"})
+
+	if err := os.RemoveAll(filepath.Join(dir, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "first.md"), []byte("rewritten\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForMatches(t, index, fsys, "rewritten", []string{"first.md"})
+
+	if index.Files() != 1 {
+		t.Errorf("Indexed %d files after removing docs, expected 1", index.Files())
+	}
+}
+
+func TestIndexSearchInvalidQuery(t *testing.T) {
+	fsys := newTestFS()
+	index := fulltext.New(fsys, fulltext.Options{})
+	refresh(t, index)
+
+	for _, query := range []string{"", "se", "  a "} {
+		if _, err := index.Search(fsys, ".", query, fulltext.SearchOptions{}); !errors.Is(err, fulltext.ErrInvalidQuery) {
+			t.Errorf("Search(%q) returned %v, expected ErrInvalidQuery", query, err)
+		}
+	}
+}
+
+func TestServerTextSearch(t *testing.T) {
+	srv, err := goserve.New(goserve.WithFS(newTestFS()), goserve.WithAddr("127.0.0.1:0"), goserve.WithTextIndex(0, 0))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	baseURL := "http://" + srv.Addrs()[0].String()
+
+	get := func(url string) string {
+		t.Helper()
+
+		resp, err := http.Get(baseURL + url)
+		if err != nil {
+			t.Fatalf("GET %s returned error: %v", url, err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// Wait for the first scan
+	var lines []string
+	for range 100 {
+		lines = strings.Split(strings.TrimSpace(get("/docs/?search=setup&content=1&format=json")), "\n")
+		if !strings.Contains(lines[len(lines)-1], `"indexing"`) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var match struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &match); err != nil || match.Path != "/docs/guide.md" || match.Line != 3 {
+		t.Errorf("JSON search returned %s, expected line 3 of /docs/guide.md", lines[0])
+	}
+
+	body := get("/?search=setup&content=1")
+	if !strings.Contains(body, `href="/docs/guide.md?view=1#L3"`) || !strings.Contains(body, "<mark") {
+		t.Errorf("Search page should link and highlight matching lines: %s", body)
+	}
+
+	if body := get("/"); !strings.Contains(body, `name="content"`) {
+		t.Errorf("Listing should offer searching contents")
+	}
+}
@@ -0,0 +1,216 @@
+package goserve_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/pkg/goserve"
+)
+
+var testRootDir = filepath.Join("..", "testdata", "root")
+
+func TestServerStartAndShutdown(t *testing.T) {
+	srv, err := goserve.New(goserve.WithRootDir(testRootDir), goserve.WithAddr("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+
+	if err := srv.Start(context.Background()); !errors.Is(err, goserve.ErrAlreadyStarted) {
+		t.Errorf("Second Start() returned %v, expected ErrAlreadyStarted", err)
+	}
+
+	addrs := srv.Addrs()
+	if len(addrs) != 1 {
+		t.Fatalf("Expected 1 address, got %v", addrs)
+	}
+
+	resp, err := http.Get("http://" + addrs[0].String() + "/file1.txt")
+	if err != nil {
+		t.Fatalf("GET returned error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() returned error: %v", err)
+	}
+
+	if _, err := http.Get("http://" + addrs[0].String() + "/file1.txt"); err == nil {
+		t.Errorf("Server still accepts connections after Shutdown()")
+	}
+}
+
+func TestServerStartReturnsListenError(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer taken.Close()
+
+	srv, err := goserve.New(goserve.WithRootDir(testRootDir), goserve.WithAddr(taken.Addr().String()))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	if err := srv.Start(context.Background()); err == nil {
+		t.Fatalf("Start() on an address in use should return an error")
+	}
+}
+
+func TestHandlerWithMiddleware(t *testing.T) {
+	var order []string
+
+	middleware := func(name string) goserve.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	srv, err := goserve.New(
+		goserve.WithRootDir(testRootDir),
+		goserve.WithMiddleware(middleware("first"), middleware("second")),
+	)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", srv.Handler())
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Get(ts.URL + "/file1.txt")
+	if err != nil {
+		t.Fatalf("GET returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("Middlewares ran in order %v, expected first,second", order)
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	tests := map[string][]goserve.Option{
+		"theme":    {goserve.WithTheme("unknown")},
+		"root dir": {goserve.WithRootDir(filepath.Join(testRootDir, "missing"))},
+		"address":  {goserve.WithAddr("localhost")},
+		"redirect": {goserve.WithPlainHTTP(":8080", http.StatusOK)},
+		// Rules without a CA would refuse every request under them
+		"client rule": {goserve.WithClientCertRule("/private", "alice")},
+	}
+
+	for name, opts := range tests {
+		if _, err := goserve.New(opts...); err == nil {
+			t.Errorf("New() with invalid %s should return an error", name)
+		}
+	}
+}
+
+func TestHandlerWithFS(t *testing.T) {
+	fsys := fstest.MapFS{"static/app.js": {Data: []byte("console.log('hi')")}}
+
+	srv, err := goserve.New(goserve.WithFS(fsys))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Get(ts.URL + "/static/app.js")
+	if err != nil {
+		t.Fatalf("GET returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "console.log('hi')" {
+		t.Errorf("Expected file content with status 200, got %d with %q", resp.StatusCode, body)
+	}
+}
+
+func TestHandlerListing(t *testing.T) {
+	srv, err := goserve.New(goserve.WithRootDir(testRootDir), goserve.WithTheme(goserve.ThemeBasic))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Get(ts.URL + "/dir1/")
+	if err != nil {
+		t.Fatalf("GET returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if !strings.Contains(string(body), `href="/"`) {
+		t.Errorf("Expected a link to the parent directory in the listing, got %s", body)
+	}
+
+	// Stylesheets of the theme are served by the same handler
+	for _, match := range regexp.MustCompile(`href="(/_goserveass/[^"]+)"`).FindAllStringSubmatch(string(body), -1) {
+		assetResp, err := http.Get(ts.URL + match[1])
+		if err != nil {
+			t.Fatalf("GET %s returned error: %v", match[1], err)
+		}
+		assetResp.Body.Close()
+
+		if assetResp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 for %s, got %d", match[1], assetResp.StatusCode)
+		}
+	}
+}
+
+func TestStartServesHandler(t *testing.T) {
+	built := 0
+	middleware := func(next http.Handler) http.Handler {
+		built++
+		return next
+	}
+
+	srv, err := goserve.New(goserve.WithRootDir(testRootDir), goserve.WithAddr("127.0.0.1:0"), goserve.WithMiddleware(middleware))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	srv.Handler()
+
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	if built != 1 {
+		t.Errorf("Expected the routes to be built once, got %d times", built)
+	}
+}
@@ -0,0 +1,215 @@
+package listener_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/listener"
+)
+
+func tempSocketPath(t *testing.T) string {
+	// Socket paths have a short length limit, so avoid the long t.TempDir() path
+	dir, err := os.MkdirTemp("", "goserve_")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	return filepath.Join(dir, "goserve.sock")
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]listener.Address{
+		"unix:/run/goserve.sock": {Network: listener.NetworkUnix, Addr: "/run/goserve.sock"},
+		"systemd":                {Network: listener.NetworkSystemd},
+		"systemd:web":            {Network: listener.NetworkSystemd, Addr: "web"},
+	}
+
+	for input, expected := range tests {
+		addr, ok := listener.Parse(input)
+		if !ok {
+			t.Fatalf("Parse(%q) did not recognise the address", input)
+		}
+
+		if addr != expected {
+			t.Errorf("Parse(%q) returned %+v, expected %+v", input, addr, expected)
+		}
+
+		if addr.String() != input {
+			t.Errorf("String() returned %q, expected %q", addr.String(), input)
+		}
+	}
+
+	for _, input := range []string{"localhost:8080", ":8080", "[::1]:8080", "systemdx"} {
+		if _, ok := listener.Parse(input); ok {
+			t.Errorf("Parse(%q) should be treated as TCP", input)
+		}
+	}
+}
+
+func TestListenUnixRemovesStaleSocket(t *testing.T) {
+	socketPath := tempSocketPath(t)
+
+	// Leave a socket file behind without anyone accepting on it
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	listeners, err := listener.Listen(listener.Address{Network: listener.NetworkUnix, Addr: socketPath}, listener.Options{})
+	if err != nil {
+		t.Fatalf("Listen() returned error: %v", err)
+	}
+	defer listeners[0].Close()
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to socket: %v", err)
+	}
+	conn.Close()
+}
+
+func TestListenUnixInUse(t *testing.T) {
+	socketPath := tempSocketPath(t)
+	addr := listener.Address{Network: listener.NetworkUnix, Addr: socketPath}
+
+	listeners, err := listener.Listen(addr, listener.Options{})
+	if err != nil {
+		t.Fatalf("Listen() returned error: %v", err)
+	}
+	defer listeners[0].Close()
+
+	_, err = listener.Listen(addr, listener.Options{})
+	if !errors.Is(err, listener.ErrSocketInUse) {
+		t.Fatalf("Listen() on a socket in use returned %v, expected ErrSocketInUse", err)
+	}
+}
+
+func TestListenUnixMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix socket file modes are not supported on Windows")
+	}
+
+	socketPath := tempSocketPath(t)
+
+	listeners, err := listener.Listen(listener.Address{Network: listener.NetworkUnix, Addr: socketPath}, listener.Options{SocketMode: 0660})
+	if err != nil {
+		t.Fatalf("Listen() returned error: %v", err)
+	}
+	defer listeners[0].Close()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %v", err)
+	}
+
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %o", info.Mode().Perm())
+	}
+}
+
+func TestListenSystemdWithoutSockets(t *testing.T) {
+	_, err := listener.Listen(listener.Address{Network: listener.NetworkSystemd}, listener.Options{})
+	if !errors.Is(err, listener.ErrNoSystemdSockets) {
+		t.Fatalf("Listen() without LISTEN_FDS returned %v, expected ErrNoSystemdSockets", err)
+	}
+}
+
+func TestListenTCPPortFallback(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer taken.Close()
+
+	addr := listener.Address{Network: listener.NetworkTCP, Addr: taken.Addr().String()}
+
+	if _, err := listener.Listen(addr, listener.Options{}); err == nil {
+		t.Fatalf("Listen() on a port in use without fallback should fail")
+	}
+
+	addr.PortFallback = true
+	listeners, err := listener.Listen(addr, listener.Options{})
+	if err != nil {
+		t.Fatalf("Listen() with fallback returned error: %v", err)
+	}
+	defer listeners[0].Close()
+
+	if listeners[0].Addr().String() == taken.Addr().String() {
+		t.Errorf("Listen() with fallback returned the port in use %s", taken.Addr())
+	}
+}
+
+func TestReachableAddrs(t *testing.T) {
+	specific := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	if reachable := listener.ReachableAddrs(specific); len(reachable) != 1 || reachable[0] != specific {
+		t.Errorf("ReachableAddrs() of a specific IP returned %v, expected only %v", reachable, specific)
+	}
+
+	for _, addr := range listener.ReachableAddrs(&net.TCPAddr{IP: net.IPv4zero, Port: 8080}) {
+		tcpAddr := addr.(*net.TCPAddr)
+
+		if tcpAddr.Port != 8080 {
+			t.Errorf("ReachableAddrs() returned %v with a different port", addr)
+		}
+
+		if tcpAddr.IP.To4() == nil && !tcpAddr.IP.IsUnspecified() {
+			t.Errorf("ReachableAddrs() of an IPv4 wildcard returned IPv6 address %v", addr)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	ln := listener.NewLimiter(1).Wrap(inner)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	for range 2 {
+		client, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		defer client.Close()
+	}
+
+	first := <-accepted
+
+	select {
+	case <-accepted:
+		t.Fatalf("Second connection accepted while the limit is reached")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.Close()
+
+	select {
+	case second := <-accepted:
+		second.Close()
+	case <-time.After(time.Second):
+		t.Fatalf("Second connection not accepted after the first closed")
+	}
+}
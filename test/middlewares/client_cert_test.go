@@ -0,0 +1,94 @@
+package middlewares_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ducng99/goserve/internal/server/middlewares"
+)
+
+var clientCertRules = []middlewares.ClientCertRule{
+	{PathPrefix: "/private/", Allowed: []string{"alice", "*.build.internal"}},
+	{PathPrefix: "/private/releases/", Allowed: []string{"release-bot"}},
+}
+
+func serveWithCert(cert *x509.Certificate, urlPath string) int {
+	handler := middlewares.ClientCertMiddleware(clientCertRules, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	if cert != nil {
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestClientCertNoRuleAllowed(t *testing.T) {
+	if code := serveWithCert(nil, "/public/file.txt"); code != http.StatusOK {
+		t.Fatalf("Expected 200 for path without rule, got %d", code)
+	}
+}
+
+func TestClientCertMissingCert(t *testing.T) {
+	if code := serveWithCert(nil, "/private/file.txt"); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without client certificate, got %d", code)
+	}
+}
+
+func TestClientCertCommonName(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}
+
+	if code := serveWithCert(cert, "/private/file.txt"); code != http.StatusOK {
+		t.Fatalf("Expected 200 for allowed CN, got %d", code)
+	}
+
+	// Longest prefix rule applies
+	if code := serveWithCert(cert, "/private/releases/v1.zip"); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for CN not allowed by the more specific rule, got %d", code)
+	}
+}
+
+func TestClientCertSANPattern(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "runner"},
+		DNSNames: []string{"ci-1.build.internal"},
+	}
+
+	if code := serveWithCert(cert, "/private/file.txt"); code != http.StatusOK {
+		t.Fatalf("Expected 200 for SAN matching pattern, got %d", code)
+	}
+}
+
+func TestClientCertNotAllowed(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "mallory"}}
+
+	if code := serveWithCert(cert, "/private/file.txt"); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for CN not allowed, got %d", code)
+	}
+}
+
+func TestClientCertPathSegments(t *testing.T) {
+	tests := map[string]int{
+		"/private":           http.StatusForbidden,
+		"/private/":          http.StatusForbidden,
+		"/privateer/map.txt": http.StatusOK,
+		"/private-notes.txt": http.StatusOK,
+	}
+
+	for urlPath, expected := range tests {
+		if code := serveWithCert(nil, urlPath); code != expected {
+			t.Errorf("Expected %d for %s without client certificate, got %d", expected, urlPath, code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package middlewares_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/server/middlewares"
+)
+
+func TestHttpsRedirect(t *testing.T) {
+	tests := []struct {
+		host      string
+		httpsPort string
+		expected  string
+	}{
+		{"example.test:8080", "8443", "https://example.test:8443/dir/file.txt?a=1"},
+		{"example.test", "443", "https://example.test/dir/file.txt?a=1"},
+		{"[::1]:8080", "443", "https://[::1]/dir/file.txt?a=1"},
+		{"[::1]:8080", "8443", "https://[::1]:8443/dir/file.txt?a=1"},
+	}
+
+	for _, test := range tests {
+		handler := middlewares.HttpsRedirectHandler(http.StatusPermanentRedirect, test.httpsPort)
+
+		req := httptest.NewRequest(http.MethodGet, "/dir/file.txt?a=1", nil)
+		req.Host = test.host
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("Host %s: expected status 308, got %d", test.host, rec.Code)
+		}
+
+		if location := rec.Header().Get("Location"); location != test.expected {
+			t.Errorf("Host %s: expected Location %q, got %q", test.host, test.expected, location)
+		}
+	}
+}
+
+func TestHstsOnlyOnTLS(t *testing.T) {
+	handler := middlewares.HstsMiddleware(24*time.Hour, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if hsts := rec.Header().Get("Strict-Transport-Security"); hsts != "" {
+		t.Errorf("Expected no HSTS header on plain HTTP, got %q", hsts)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if hsts := rec.Header().Get("Strict-Transport-Security"); hsts != "max-age=86400" {
+		t.Errorf("Expected HSTS header max-age=86400, got %q", hsts)
+	}
+}
@@ -0,0 +1,111 @@
+package middlewares_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ducng99/goserve/internal/server/middlewares"
+)
+
+func TestInFlightTracksRequests(t *testing.T) {
+	inFlight := middlewares.NewInFlight()
+
+	written := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.Write([]byte("hello"))
+		close(written)
+		<-release
+		w.Write([]byte("world"))
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/big.bin", nil))
+		close(done)
+	}()
+
+	<-written
+
+	requests := inFlight.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 in-flight request, got %d", len(requests))
+	}
+
+	request := requests[0]
+	if request.Method != http.MethodGet || request.Path != "/big.bin" {
+		t.Errorf("Unexpected in-flight request %s %s", request.Method, request.Path)
+	}
+
+	if request.BytesWritten != 5 || request.Size != 10 {
+		t.Errorf("Expected 5 of 10 bytes written, got %d of %d", request.BytesWritten, request.Size)
+	}
+
+	close(release)
+	<-done
+
+	if inFlight.Len() != 0 {
+		t.Errorf("Expected no in-flight requests after serving, got %d", inFlight.Len())
+	}
+}
+
+func TestInFlightFlushes(t *testing.T) {
+	handler := middlewares.NewInFlight().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatalf("Tracked writer should be a http.Flusher")
+		}
+
+		w.Write([]byte("partial"))
+		flusher.Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rec.Flushed {
+		t.Errorf("Flush should reach the underlying writer")
+	}
+}
+
+// Records whether the body was copied with ReadFrom
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (w *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func TestInFlightReadFrom(t *testing.T) {
+	inFlight := middlewares.NewInFlight()
+
+	var written int64
+	handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readerFrom, ok := w.(io.ReaderFrom)
+		if !ok {
+			t.Fatalf("Tracked writer should be an io.ReaderFrom")
+		}
+
+		readerFrom.ReadFrom(strings.NewReader("hello world"))
+		written = inFlight.Requests()[0].BytesWritten
+	}))
+
+	rec := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rec.readFrom || rec.Body.String() != "hello world" {
+		t.Errorf("ReadFrom should reach the underlying writer")
+	}
+
+	if written != 11 {
+		t.Errorf("Expected 11 bytes written, got %d", written)
+	}
+}
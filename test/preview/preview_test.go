@@ -0,0 +1,177 @@
+package preview_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ducng99/goserve/internal/preview"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+var previewTestFS = fstest.MapFS{
+	"README.md":  {Data: []byte("# Title\n\nHello <script>alert(1)</script> [link](javascript:alert(1)) **bold**\n")},
+	"main.go":    {Data: []byte("package main\n\nfunc main() {}\n")},
+	"notes":      {Data: []byte("plain text without an extension\n")},
+	"blob":       {Data: []byte("\x00\x01\x02\x03")},
+	"photo.png":  {Data: []byte("\x89PNG")},
+	"clip.mp4":   {Data: []byte("")},
+	"song.mp3":   {Data: []byte("")},
+	"manual.pdf": {Data: []byte("%PDF-1.4")},
+	"huge.txt":   {Data: []byte(strings.Repeat("a", preview.MaxTextSize+1))},
+
+	"docs/README.txt": {Data: []byte("Plain <b>text</b>\n")},
+	"docs/index.md":   {Data: []byte("# Index\n")},
+	"wiki/index.md":   {Data: []byte("# Wiki\n")},
+}
+
+func TestKindOf(t *testing.T) {
+	tests := map[string]preview.Kind{
+		"README.md":  preview.KindMarkdown,
+		"main.go":    preview.KindCode,
+		"notes":      preview.KindCode,
+		"blob":       preview.KindNone,
+		"photo.png":  preview.KindImage,
+		"clip.mp4":   preview.KindVideo,
+		"song.mp3":   preview.KindAudio,
+		"manual.pdf": preview.KindPDF,
+	}
+
+	for name, expected := range tests {
+		if kind := preview.KindOf(previewTestFS, name); kind != expected {
+			t.Errorf("KindOf(%s) = %q, expected %q", name, kind, expected)
+		}
+	}
+}
+
+func TestRenderMarkdownIsSanitised(t *testing.T) {
+	html, err := preview.RenderMarkdown(previewTestFS["README.md"].Data)
+	if err != nil {
+		t.Fatalf("RenderMarkdown() returned error: %v", err)
+	}
+
+	if !strings.Contains(html, "<strong>bold</strong>") || !strings.Contains(html, `<h1 id="title">`) {
+		t.Errorf("RenderMarkdown() did not render Markdown: %s", html)
+	}
+	if strings.Contains(html, "<script") || strings.Contains(html, "javascript:") {
+		t.Errorf("RenderMarkdown() kept unsafe HTML: %s", html)
+	}
+}
+
+func TestHighlightHasLineAnchors(t *testing.T) {
+	html, err := preview.Highlight("main.go", previewTestFS["main.go"].Data)
+	if err != nil {
+		t.Fatalf("Highlight() returned error: %v", err)
+	}
+
+	for _, expected := range []string{`id="L1"`, `href="#L3"`, `class="kn"`} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Highlight() should contain %s: %s", expected, html)
+		}
+	}
+	if strings.Contains(html, "style=") {
+		t.Errorf("Highlight() should not use inline styles, which the CSP blocks")
+	}
+}
+
+func TestRenderTooLarge(t *testing.T) {
+	if _, err := preview.Render(previewTestFS, "huge.txt", preview.KindCode); !errors.Is(err, preview.ErrTooLarge) {
+		t.Errorf("Render() of a large file returned %v, expected ErrTooLarge", err)
+	}
+}
+
+func TestServerPreview(t *testing.T) {
+	for _, theme := range []string{"basic", "pretty"} {
+		config := server.ServerConfig{FS: previewTestFS, DirViewTheme: theme}
+
+		mux, err := config.NewServeMux()
+		if err != nil {
+			t.Fatalf("NewServeMux() returned error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/README.md?view=1", nil))
+
+		if !strings.Contains(rec.Body.String(), "<strong>bold</strong>") || !strings.Contains(rec.Body.String(), `href="/README.md"`) {
+			t.Errorf("%s theme should render Markdown with a link to the raw file", theme)
+		}
+		if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "'nonce-") {
+			t.Errorf("%s theme preview should have a nonce CSP, got %q", theme, csp)
+		}
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clip.mp4?view=1", nil))
+
+		if !strings.Contains(rec.Body.String(), `<video`) {
+			t.Errorf("%s theme should show videos in a player", theme)
+		}
+
+		// Files are served as they are without the parameter
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/README.md", nil))
+
+		if !strings.HasPrefix(rec.Body.String(), "# Title") {
+			t.Errorf("%s theme should serve raw files without ?view=1", theme)
+		}
+
+		// Listings toggled to previews link files to them
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?view=1", nil))
+
+		if !strings.Contains(rec.Body.String(), `href="/main.go?view=1"`) {
+			t.Errorf("%s theme listing should link to previews when toggled", theme)
+		}
+	}
+}
+
+func TestFindReadme(t *testing.T) {
+	tests := []struct {
+		dir      string
+		name     string
+		contains string
+	}{
+		{".", "README.md", "<strong>bold</strong>"},
+		{"docs", "README.txt", "<pre>Plain &lt;b&gt;text&lt;/b&gt;"},
+		{"wiki", "index.md", "<h1 id=\"wiki\">Wiki</h1>"},
+	}
+
+	for _, test := range tests {
+		readme, err := preview.FindReadme(previewTestFS, test.dir)
+		if err != nil || readme == nil {
+			t.Fatalf("FindReadme(%s) returned %v, %v", test.dir, readme, err)
+		}
+
+		if readme.Name != test.name || !strings.Contains(readme.HTML, test.contains) {
+			t.Errorf("FindReadme(%s) found %s: %s, expected %s containing %s", test.dir, readme.Name, readme.HTML, test.name, test.contains)
+		}
+	}
+
+	if readme, err := preview.FindReadme(fstest.MapFS{"main.go": {}}, "."); readme != nil || err != nil {
+		t.Errorf("FindReadme() without a readme returned %v, %v", readme, err)
+	}
+}
+
+func TestServerReadme(t *testing.T) {
+	for _, theme := range []string{"basic", "pretty"} {
+		config := server.ServerConfig{FS: previewTestFS, DirViewTheme: theme}
+
+		mux, err := config.NewServeMux()
+		if err != nil {
+			t.Fatalf("NewServeMux() returned error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		body := rec.Body.String()
+		if !strings.Contains(body, "<strong>bold</strong>") || strings.Contains(body, "<script>alert") {
+			t.Errorf("%s theme should show the sanitised readme below the listing", theme)
+		}
+		if strings.Index(body, "<strong>bold</strong>") < strings.Index(body, `href="/main.go"`) {
+			t.Errorf("%s theme should show the readme after the entries", theme)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/acme"
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/ssl"
+)
+
+// Gets a TCP port nothing listens on
+func freePort(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func TestPlainServerAnswersAcmeOnWildcardAddress(t *testing.T) {
+	keyPair, err := ssl.NewKeys(time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeys() returned error: %v", err)
+	}
+
+	certPath, keyPath, err := keyPair.Save(t.TempDir())
+	if err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	port := freePort(t)
+
+	for _, addrs := range [][2]string{
+		{"0.0.0.0:" + port, ":" + port},
+		{":" + port, "0.0.0.0:" + port},
+		{"[::]:" + port, "127.0.0.1:" + port},
+	} {
+		config := &server.ServerConfig{
+			RootDir:      testRootDir,
+			Host:         "127.0.0.1",
+			Port:         "0",
+			CertPath:     certPath,
+			KeyPath:      keyPath,
+			HttpAddr:     addrs[0],
+			HttpRedirect: http.StatusMovedPermanently,
+			AcmeEnabled:  true,
+			// The certificate is requested in the background, the CA being unreachable does not matter here
+			Acme:         acme.Config{DirectoryURL: "http://127.0.0.1:1/dir", Domains: []string{"app.test"}, CacheDir: t.TempDir()},
+			AcmeHTTPAddr: addrs[1],
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Listening for challenges on the same port again would fail
+		if err := config.Start(ctx); err != nil {
+			cancel()
+			t.Fatalf("Start() with plain HTTP on %s and challenges on %s returned error: %v", addrs[0], addrs[1], err)
+		}
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get("http://127.0.0.1:" + port + "/.well-known/acme-challenge/unknown")
+		if err == nil {
+			resp.Body.Close()
+
+			// Unknown tokens are not found rather than redirected to HTTPS
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("Plain HTTP on %s should answer challenges, got status %d", addrs[0], resp.StatusCode)
+			}
+		} else {
+			t.Errorf("GET returned error: %v", err)
+		}
+
+		config.Close()
+		cancel()
+	}
+}
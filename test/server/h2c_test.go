@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ducng99/goserve/internal/proxy"
+	"github.com/ducng99/goserve/internal/server"
+)
+
+func newH2cClient() *http.Client {
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+
+	return &http.Client{Transport: &http.Transport{Protocols: protocols}}
+}
+
+func TestH2cPriorKnowledge(t *testing.T) {
+	config := server.ServerConfig{
+		RootDir:    testRootDir,
+		H2cEnabled: true,
+	}
+
+	ts := httptest.NewServer(server.H2cHandler(newTestMux(t, &config)))
+	t.Cleanup(ts.Close)
+
+	resp, err := newH2cClient().Get(ts.URL + "/file1.txt")
+	if err != nil {
+		t.Fatalf("Failed to get file1.txt over h2c: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2 response, got %s", resp.Proto)
+	}
+}
+
+func TestH2cStillServesHTTP1(t *testing.T) {
+	config := server.ServerConfig{
+		RootDir:    testRootDir,
+		H2cEnabled: true,
+	}
+
+	ts := httptest.NewServer(server.H2cHandler(newTestMux(t, &config)))
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Get(ts.URL + "/file1.txt")
+	if err != nil {
+		t.Fatalf("Failed to get file1.txt over HTTP/1.1: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 1 {
+		t.Errorf("Expected HTTP/1.1 response, got %s", resp.Proto)
+	}
+}
+
+func TestProxyToH2cUpstream(t *testing.T) {
+	upstream := httptest.NewServer(server.H2cHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})))
+	t.Cleanup(upstream.Close)
+
+	proxyHandler, err := proxy.New(upstream.URL, false, false, true)
+	if err != nil {
+		t.Fatalf("proxy.New() returned error: %v", err)
+	}
+
+	front := httptest.NewServer(proxyHandler)
+	t.Cleanup(front.Close)
+
+	resp, err := http.Get(front.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to get through proxy: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("Expected upstream to receive HTTP/2.0, got %q", string(body))
+	}
+}
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/ssl"
+	"github.com/quic-go/quic-go/http3"
+)
+
+var testRootDir = filepath.Join("..", "testdata", "root")
+
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	keyPair, err := ssl.NewKeys(time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeys() returned error: %v", err)
+	}
+
+	cert, err := tls.X509KeyPair(keyPair.Cert.Bytes(), keyPair.Key.Bytes())
+	if err != nil {
+		t.Fatalf("X509KeyPair() returned error: %v", err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+
+func newTestMux(t *testing.T, config *server.ServerConfig) *http.ServeMux {
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	return mux
+}
+
+func TestHttp3ServesFiles(t *testing.T) {
+	config := server.ServerConfig{
+		RootDir:      testRootDir,
+		Http3Enabled: true,
+	}
+
+	h3Server, addr, err := server.ListenHttp3("127.0.0.1:0", &http.Server{Handler: newTestMux(t, &config), TLSConfig: newTestTLSConfig(t)}, nil)
+	if err != nil {
+		t.Fatalf("ListenHttp3() returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		h3Server.Close()
+	})
+
+	transport := &http3.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	t.Cleanup(func() {
+		transport.Close()
+	})
+
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	resp, err := client.Get("https://" + addr.String() + "/file1.txt")
+	if err != nil {
+		t.Fatalf("Failed to get file1.txt over HTTP/3: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if resp.ProtoMajor != 3 {
+		t.Errorf("Expected HTTP/3 response, got %s", resp.Proto)
+	}
+
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != "" {
+		t.Errorf("Expected no Alt-Svc header over HTTP/3, got %q", altSvc)
+	}
+}
+
+func TestHttp3AdvertisedByAltSvc(t *testing.T) {
+	config := server.ServerConfig{
+		RootDir:      testRootDir,
+		Port:         "8443",
+		Http3Enabled: true,
+	}
+
+	ts := httptest.NewTLSServer(newTestMux(t, &config))
+	t.Cleanup(ts.Close)
+
+	resp, err := ts.Client().Get(ts.URL + "/file1.txt")
+	if err != nil {
+		t.Fatalf("Failed to get file1.txt: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != `h3=":8443"; ma=86400` {
+		t.Errorf("Unexpected Alt-Svc header %q", altSvc)
+	}
+}
@@ -0,0 +1,63 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/ssl"
+)
+
+func TestPlainServerRedirectsWithClientCA(t *testing.T) {
+	keyPair, err := ssl.NewKeys(time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeys() returned error: %v", err)
+	}
+
+	certPath, keyPath, err := keyPair.Save(t.TempDir())
+	if err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	port := freePort(t)
+
+	for _, mode := range []string{server.ClientAuthRequire, server.ClientAuthOptional} {
+		config := &server.ServerConfig{
+			RootDir:  testRootDir,
+			Host:     "127.0.0.1",
+			Port:     "0",
+			CertPath: certPath,
+			KeyPath:  keyPath,
+			HttpAddr: "127.0.0.1:" + port,
+			// Any certificate will do as the CA, no client connects over HTTPS
+			ClientCAPath: certPath,
+			ClientAuth:   mode,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		if err := config.Start(ctx); err != nil {
+			cancel()
+			t.Fatalf("Start() returned error: %v", err)
+		}
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get("http://127.0.0.1:" + port + "/")
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusPermanentRedirect {
+				t.Errorf("Plain HTTP with %s client auth returned status %d with %q, expected a redirect to HTTPS", mode, resp.StatusCode, body)
+			}
+		} else {
+			t.Errorf("GET returned error: %v", err)
+		}
+
+		config.Close()
+		cancel()
+	}
+}
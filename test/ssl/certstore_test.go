@@ -0,0 +1,96 @@
+package ssl_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ducng99/goserve/internal/ssl"
+)
+
+func newTestCert(t *testing.T) *tls.Certificate {
+	keyPair, err := ssl.NewKeys(time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeys() returned error: %v", err)
+	}
+
+	cert, err := tls.X509KeyPair(keyPair.Cert.Bytes(), keyPair.Key.Bytes())
+	if err != nil {
+		t.Fatalf("X509KeyPair() returned error: %v", err)
+	}
+
+	return &cert
+}
+
+func TestCertStoreSelectsBySNI(t *testing.T) {
+	appCert := newTestCert(t)
+	apiCert := newTestCert(t)
+	defaultCert := newTestCert(t)
+
+	store := ssl.NewCertStore()
+	store.Set("app.test", appCert)
+	store.Set("API.test", apiCert)
+	store.SetDefault(defaultCert)
+
+	tests := map[string]*tls.Certificate{
+		"app.test":   appCert,
+		"api.test":   apiCert,
+		"APP.TEST.":  appCert,
+		"other.test": defaultCert,
+		"":           defaultCert,
+	}
+
+	for serverName, expected := range tests {
+		cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		if err != nil {
+			t.Fatalf("GetCertificate(%q) returned error: %v", serverName, err)
+		}
+
+		if cert != expected {
+			t.Errorf("GetCertificate(%q) returned the wrong certificate", serverName)
+		}
+	}
+}
+
+func TestCertStoreWildcard(t *testing.T) {
+	wildcardCert := newTestCert(t)
+
+	store := ssl.NewCertStore()
+	store.Set("*.test", wildcardCert)
+
+	cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.test"})
+	if err != nil {
+		t.Fatalf("GetCertificate() returned error: %v", err)
+	}
+
+	if cert != wildcardCert {
+		t.Fatalf("GetCertificate() did not return the wildcard certificate")
+	}
+
+	if store.Lookup("a.b.test") != nil {
+		t.Fatalf("Lookup() matched a wildcard across multiple labels")
+	}
+}
+
+func TestCertStoreNoDefault(t *testing.T) {
+	store := ssl.NewCertStore()
+
+	_, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.test"})
+	if !errors.Is(err, ssl.ErrNoCertificate) {
+		t.Fatalf("GetCertificate() returned %v, expected ErrNoCertificate", err)
+	}
+}
+
+func TestCertStoreSwap(t *testing.T) {
+	oldCert := newTestCert(t)
+	newCert := newTestCert(t)
+
+	store := ssl.NewCertStore()
+	store.Set("app.test", oldCert)
+	store.Set("app.test", newCert)
+
+	if store.Lookup("app.test") != newCert {
+		t.Fatalf("Lookup() did not return the replaced certificate")
+	}
+}
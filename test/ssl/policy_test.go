@@ -0,0 +1,65 @@
+package ssl_test
+
+import (
+	"crypto/tls"
+	"slices"
+	"testing"
+
+	"github.com/ducng99/goserve/internal/ssl"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]uint16{
+		"":       0,
+		"1.2":    tls.VersionTLS12,
+		"1.3":    tls.VersionTLS13,
+		"TLS1.3": tls.VersionTLS13,
+	}
+
+	for input, expected := range tests {
+		version, err := ssl.ParseVersion(input)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q) returned error: %v", input, err)
+		}
+
+		if version != expected {
+			t.Errorf("ParseVersion(%q) returned %x, expected %x", input, version, expected)
+		}
+	}
+
+	if _, err := ssl.ParseVersion("2.0"); err == nil {
+		t.Errorf("ParseVersion(\"2.0\") returned no error")
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ssl.ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "tls_ecdhe_rsa_with_chacha20_poly1305_sha256"})
+	if err != nil {
+		t.Fatalf("ParseCipherSuites() returned error: %v", err)
+	}
+
+	expected := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}
+	if !slices.Equal(ids, expected) {
+		t.Errorf("ParseCipherSuites() returned %v, expected %v", ids, expected)
+	}
+
+	if _, err := ssl.ParseCipherSuites([]string{"TLS_NOT_A_SUITE"}); err == nil {
+		t.Errorf("ParseCipherSuites() returned no error for unknown suite")
+	}
+}
+
+func TestParseCurves(t *testing.T) {
+	ids, err := ssl.ParseCurves([]string{"X25519", "P-256", "CurveP384"})
+	if err != nil {
+		t.Fatalf("ParseCurves() returned error: %v", err)
+	}
+
+	expected := []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}
+	if !slices.Equal(ids, expected) {
+		t.Errorf("ParseCurves() returned %v, expected %v", ids, expected)
+	}
+
+	if _, err := ssl.ParseCurves([]string{"P192"}); err == nil {
+		t.Errorf("ParseCurves() returned no error for unknown curve")
+	}
+}
@@ -0,0 +1,198 @@
+package thumbnail_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ducng99/goserve/internal/server"
+	"github.com/ducng99/goserve/internal/thumbnail"
+)
+
+func encodeImage(t *testing.T, width, height int, opaque bool) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if opaque {
+		for x := range width {
+			for y := range height {
+				img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if opaque {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatalf("Cannot encode test image: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func newTestFS(t *testing.T) fstest.MapFS {
+	return fstest.MapFS{
+		"shots/wide.jpg":  {Data: encodeImage(t, 1600, 900, true), ModTime: time.Unix(1, 0)},
+		"shots/tall.jpg":  {Data: encodeImage(t, 300, 1200, true)},
+		"shots/icon.png":  {Data: encodeImage(t, 40, 30, false)},
+		"shots/clip.mp4":  {Data: []byte("")},
+		"shots/movie.mp4": {Data: []byte("")},
+		"shots/movie.png": {Data: encodeImage(t, 640, 480, false)},
+		"shots/notes.txt": {Data: []byte("notes")},
+		"shots/bad.jpg":   {Data: []byte("not a jpeg")},
+	}
+}
+
+func TestMake(t *testing.T) {
+	fsys := newTestFS(t)
+
+	tests := []struct {
+		name          string
+		width, height int
+		contentType   string
+	}{
+		{"shots/wide.jpg", thumbnail.Size, 180, "image/jpeg"},
+		{"shots/tall.jpg", 80, thumbnail.Size, "image/jpeg"},
+		{"shots/icon.png", 40, 30, "image/png"},
+	}
+
+	for _, test := range tests {
+		thumb, err := thumbnail.Make(fsys, test.name)
+		if err != nil {
+			t.Fatalf("Make(%s) returned error: %v", test.name, err)
+		}
+
+		config, _, err := image.DecodeConfig(bytes.NewReader(thumb.Data))
+		if err != nil {
+			t.Fatalf("Make(%s) made an invalid image: %v", test.name, err)
+		}
+
+		if config.Width != test.width || config.Height != test.height || thumb.Type != test.contentType {
+			t.Errorf("Make(%s) made a %dx%d %s, expected %dx%d %s", test.name, config.Width, config.Height, thumb.Type, test.width, test.height, test.contentType)
+		}
+	}
+
+	for _, name := range []string{"shots/notes.txt", "shots/bad.jpg"} {
+		if _, err := thumbnail.Make(fsys, name); !errors.Is(err, thumbnail.ErrUnsupported) {
+			t.Errorf("Make(%s) returned %v, expected ErrUnsupported", name, err)
+		}
+	}
+}
+
+func TestMakeLargeFile(t *testing.T) {
+	// A small image padded past the largest file read
+	content := append(encodeImage(t, 10, 10, true), make([]byte, 32<<20)...)
+	fsys := fstest.MapFS{"large.jpg": {Data: content}}
+
+	if _, err := thumbnail.Make(fsys, "large.jpg"); !errors.Is(err, thumbnail.ErrUnsupported) || !strings.Contains(err.Error(), "bytes is too large") {
+		t.Errorf("Make() returned %v, expected the file to be too large", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	fsys := newTestFS(t)
+	cache := thumbnail.NewCache(0)
+
+	first, err := cache.Get(fsys, "", "shots/wide.jpg")
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+
+	if second, _ := cache.Get(fsys, "", "shots/wide.jpg"); second != first {
+		t.Errorf("Get() should return the cached thumbnail")
+	}
+
+	if other, _ := cache.Get(fsys, "v1", "shots/wide.jpg"); other == first {
+		t.Errorf("Get() should not share thumbnails between namespaces")
+	}
+
+	// Changed images get a new thumbnail
+	fsys["shots/wide.jpg"] = &fstest.MapFile{Data: encodeImage(t, 800, 800, true), ModTime: time.Unix(2, 0)}
+
+	changed, _ := cache.Get(fsys, "", "shots/wide.jpg")
+	if changed == first || !changed.ModTime.Equal(time.Unix(2, 0)) {
+		t.Errorf("Get() should make a new thumbnail of a changed image")
+	}
+}
+
+func TestCacheEvictsOldest(t *testing.T) {
+	fsys := newTestFS(t)
+
+	wide, _ := thumbnail.Make(fsys, "shots/wide.jpg")
+	cache := thumbnail.NewCache(int64(len(wide.Data)) + 1)
+
+	first, _ := cache.Get(fsys, "", "shots/wide.jpg")
+	cache.Get(fsys, "", "shots/tall.jpg")
+
+	if again, _ := cache.Get(fsys, "", "shots/wide.jpg"); again == first {
+		t.Errorf("Get() should have dropped the least recently used thumbnail")
+	}
+}
+
+func TestServerGallery(t *testing.T) {
+	config := server.ServerConfig{FS: newTestFS(t), DirViewTheme: "gallery"}
+
+	mux, err := config.NewServeMux()
+	if err != nil {
+		t.Fatalf("NewServeMux() returned error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shots/", nil))
+
+	body := rec.Body.String()
+	for _, expected := range []string{
+		`<img src="/shots/wide.jpg?thumb=1" alt="wide.jpg" loading="lazy"`,
+		`data-lightbox="video" data-src="/shots/clip.mp4"`,
+		`<img src="/shots/clip.mp4?thumb=1" data-video="/shots/clip.mp4"`,
+		`href="/shots/notes.txt"`,
+		`id="lightbox"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Gallery should contain %s", expected)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shots/wide.jpg?thumb=1", nil))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("Thumbnail returned status %d and type %s, expected a JPEG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// Videos get the thumbnail of the image next to them, or none
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shots/movie.mp4?thumb=1", nil))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("Thumbnail of a video with a poster returned status %d and type %s, expected a PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shots/clip.mp4?thumb=1", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Thumbnail of a video without a poster returned status %d, expected 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shots/notes.txt?thumb=1", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Thumbnail of a text file returned status %d, expected 404", rec.Code)
+	}
+}